@@ -0,0 +1,54 @@
+package spcdb
+
+import (
+	"context"
+	"sync"
+)
+
+type longQueryKey struct{}
+
+// WithLongQuery marks the queries run with ctx as long-running analytical
+// ones: they are routed to the analytics pool and kept out of the latency
+// figures of the regular pool.
+func WithLongQuery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, longQueryKey{}, true)
+}
+
+func IsLongQuery(ctx context.Context) bool {
+	long, _ := ctx.Value(longQueryKey{}).(bool)
+	return long
+}
+
+var analyticsPools = make(map[string]string)
+var mAnalytics sync.RWMutex
+
+// SetAnalyticsPool routes long queries meant for connectionName to the
+// pool registered as analyticsName.
+func SetAnalyticsPool(connectionName, analyticsName string) {
+	mAnalytics.Lock()
+	defer mAnalytics.Unlock()
+	if analyticsName == "" {
+		delete(analyticsPools, connectionName)
+		return
+	}
+	analyticsPools[connectionName] = analyticsName
+}
+
+// GetFromPoolContext is GetFromPool honouring WithLongQuery and
+// WithReadPreference.
+func GetFromPoolContext(ctx context.Context, connectionName string) (*DB, error) {
+	if IsLongQuery(ctx) {
+		mAnalytics.RLock()
+		name, found := analyticsPools[connectionName]
+		mAnalytics.RUnlock()
+		if found {
+			connectionName = name
+		}
+	}
+	if readPreferenceFrom(ctx) == ReadReplica {
+		if db, ok := getFromReplica(connectionName); ok {
+			return db, nil
+		}
+	}
+	return GetFromPool(connectionName)
+}
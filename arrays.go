@@ -0,0 +1,288 @@
+package spcdb
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodeArray decodes a Postgres array of the given database type name,
+// e.g. "_INT4", into the plain slice Records hold. Arrays with NULL
+// elements decode into slices of pointers, e.g. []*int64, with nil for
+// the NULLs; bytea arrays hold them as nil []byte.
+func decodeArray(dbType string, value interface{}) (interface{}, bool, error) {
+	var kind string
+	switch dbType {
+	case "_INT2", "_INT4", "_INT8", "_OID":
+		kind = "int"
+	case "_FLOAT4", "_FLOAT8":
+		kind = "float"
+	case "_BOOL":
+		kind = "bool"
+	case "_TEXT", "_VARCHAR", "_BPCHAR", "_NAME", "_CHAR", "_UUID", "_CITEXT":
+		kind = "string"
+	case "_BYTEA":
+		kind = "bytea"
+	default:
+		return nil, false, nil
+	}
+
+	var src []byte
+	switch v := value.(type) {
+	case []byte:
+		src = v
+	case string:
+		src = []byte(v)
+	default:
+		return nil, true, fmt.Errorf("spcdb: Cannot decode %T as %s", value, dbType)
+	}
+	elems, err := parseArray(src)
+	if err != nil {
+		return nil, true, err
+	}
+
+	switch kind {
+	case "int":
+		return decodeElems(elems, func(elem []byte) (int64, error) {
+			return strconv.ParseInt(string(elem), 10, 64)
+		})
+	case "float":
+		return decodeElems(elems, func(elem []byte) (float64, error) {
+			return strconv.ParseFloat(string(elem), 64)
+		})
+	case "bool":
+		return decodeElems(elems, func(elem []byte) (bool, error) {
+			return len(elem) == 1 && elem[0] == 't', nil
+		})
+	case "string":
+		return decodeElems(elems, func(elem []byte) (string, error) {
+			return string(elem), nil
+		})
+	}
+	ret := make([][]byte, len(elems))
+	for i, elem := range elems {
+		if elem == nil {
+			continue
+		}
+		if !bytes.HasPrefix(elem, []byte(`\x`)) {
+			return nil, true, fmt.Errorf("spcdb: Unsupported bytea format in %s", dbType)
+		}
+		if ret[i], err = hex.DecodeString(string(elem[2:])); err != nil {
+			return nil, true, err
+		}
+	}
+	return ret, true, nil
+}
+
+// decodeElems decodes the elements into a []T, or a []*T when some are
+// NULL.
+func decodeElems[T any](elems [][]byte, decode func([]byte) (T, error)) (interface{}, bool, error) {
+	ret := make([]T, len(elems))
+	nulls := false
+	for i, elem := range elems {
+		if elem == nil {
+			nulls = true
+			continue
+		}
+		var err error
+		if ret[i], err = decode(elem); err != nil {
+			return nil, true, err
+		}
+	}
+	if !nulls {
+		return ret, true, nil
+	}
+	ptrs := make([]*T, len(elems))
+	for i, elem := range elems {
+		if elem != nil {
+			ptrs[i] = &ret[i]
+		}
+	}
+	return ptrs, true, nil
+}
+
+// parseArray splits the text form of a one-dimensional Postgres array;
+// NULL elements are nil.
+func parseArray(src []byte) ([][]byte, error) {
+	if len(src) < 2 || src[0] != '{' || src[len(src)-1] != '}' {
+		return nil, fmt.Errorf("spcdb: Unable to parse array '%s'", src)
+	}
+	src = src[1 : len(src)-1]
+	elems := make([][]byte, 0)
+	if len(src) == 0 {
+		return elems, nil
+	}
+	for i := 0; i <= len(src); {
+		if i < len(src) && src[i] == '{' {
+			return nil, fmt.Errorf("spcdb: Multidimensional arrays are not supported")
+		}
+		var elem []byte
+		if i < len(src) && src[i] == '"' {
+			elem = []byte{}
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+				if i < len(src) {
+					elem = append(elem, src[i])
+				}
+			}
+			i++
+		} else {
+			start := i
+			for i < len(src) && src[i] != ',' {
+				i++
+			}
+			elem = src[start:i]
+			if string(elem) == "NULL" {
+				elem = nil
+			}
+		}
+		elems = append(elems, elem)
+		if i < len(src) && src[i] != ',' {
+			return nil, fmt.Errorf("spcdb: Unable to parse array '%s'", src)
+		}
+		i++
+	}
+	return elems, nil
+}
+
+// pgArray binds a Go slice as a Postgres array literal.
+type pgArray struct {
+	v reflect.Value
+}
+
+func (a pgArray) Value() (driver.Value, error) {
+	var buf strings.Builder
+	if err := writeArray(&buf, a.v); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+func writeArray(buf *strings.Builder, v reflect.Value) error {
+	buf.WriteByte('{')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeArrayElem(buf, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func writeArrayElem(buf *strings.Builder, v reflect.Value) error {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		buf.WriteString("NULL")
+		return nil
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			buf.WriteString("NULL")
+			return nil
+		}
+		v = reflect.ValueOf(value)
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte('t')
+		} else {
+			buf.WriteByte('f')
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.String:
+		writeQuoted(buf, v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			writeQuoted(buf, `\x`+hex.EncodeToString(b))
+			return nil
+		}
+		return writeArray(buf, v)
+	default:
+		if t, ok := v.Interface().(time.Time); ok {
+			writeQuoted(buf, t.Format(time.RFC3339Nano))
+			return nil
+		}
+		return fmt.Errorf("spcdb: Cannot bind %s as array element", v.Type())
+	}
+	return nil
+}
+
+func writeQuoted(buf *strings.Builder, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	buf.WriteByte('"')
+}
+
+// bindArgs converts big numbers to decimal text, registered enums to
+// their strings and, for Postgres drivers, slice arguments to array
+// literals.
+func bindArgs(driverName string, args []interface{}) []interface{} {
+	arrays := isPostgres(driverName)
+	var ret []interface{}
+	for i, arg := range args {
+		bound, converted := encodeNumeric(arg)
+		if !converted {
+			bound, converted = encodeEnum(arg)
+		}
+		if !converted {
+			if !arrays || !isArrayArg(arg) {
+				continue
+			}
+			bound = pgArray{reflect.ValueOf(arg)}
+		}
+		if ret == nil {
+			ret = make([]interface{}, len(args))
+			copy(ret, args)
+		}
+		ret[i] = bound
+	}
+	if ret == nil {
+		return args
+	}
+	return ret
+}
+
+func isArrayArg(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(arg)
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+	// []byte is bytea, not an array.
+	return t.Elem().Kind() != reflect.Uint8
+}
@@ -0,0 +1,31 @@
+package spcdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeArrayNullElements(t *testing.T) {
+	one, three := int64(1), int64(3)
+	a, b := "a", "NULL"
+	tests := []struct {
+		dbType string
+		src    string
+		want   interface{}
+	}{
+		{"_INT4", "{1,2,3}", []int64{1, 2, 3}},
+		{"_INT4", "{1,NULL,3}", []*int64{&one, nil, &three}},
+		{"_TEXT", `{a,NULL,"NULL"}`, []*string{&a, nil, &b}},
+		{"_BYTEA", `{"\\x01",NULL}`, [][]byte{{1}, nil}},
+	}
+	for _, tt := range tests {
+		got, ok, err := decodeArray(tt.dbType, []byte(tt.src))
+		if !ok || err != nil {
+			t.Errorf("decodeArray(%s, %s): %v, %v", tt.dbType, tt.src, ok, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("decodeArray(%s, %s) = %#v, want %#v", tt.dbType, tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Package arrowexport streams query results into Arrow IPC or Parquet
+// files for analytics pipelines. Rows are read through spcdb.QueryCursor,
+// so exports of any size run in bounded memory.
+package arrowexport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+	"github.com/jenchik/spcdb"
+)
+
+type Format int
+
+const (
+	// IPC is the Arrow IPC stream format.
+	IPC Format = iota
+	// Parquet closes w when done if it is an io.Closer.
+	Parquet
+)
+
+// BatchRows is the number of rows per record batch, or row group. The
+// column types come from the database types of the result columns.
+var BatchRows = 10000
+
+// Export runs the query and writes its rows to w in the format, returning
+// the number of rows written.
+func Export(ctx context.Context, db *spcdb.DB, w io.Writer, format Format, query string, args ...interface{}) (int64, error) {
+	e := &exporter{w: w, format: format}
+	err := db.QueryCursorContext(ctx, query, BatchRows, func(rec spcdb.Record) error {
+		e.batch = append(e.batch, rec)
+		if len(e.batch) < BatchRows {
+			return nil
+		}
+		return e.flush()
+	}, args...)
+	if err == nil {
+		err = e.flush()
+	}
+	if err == nil && e.writer == nil {
+		// No rows: still write the empty file, with no columns.
+		err = e.open(arrow.NewSchema(nil, nil))
+	}
+	if e.writer != nil {
+		if cerr := e.writer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return e.rows, err
+}
+
+type batchWriter interface {
+	Write(rec arrow.Record) error
+	Close() error
+}
+
+type exporter struct {
+	w      io.Writer
+	format Format
+	schema *arrow.Schema
+	writer batchWriter
+	batch  []spcdb.Record
+	rows   int64
+}
+
+func (e *exporter) open(schema *arrow.Schema) error {
+	e.schema = schema
+	if e.format == Parquet {
+		fw, err := pqarrow.NewFileWriter(schema, e.w, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+		if err != nil {
+			return err
+		}
+		e.writer = fw
+		return nil
+	}
+	e.writer = ipc.NewWriter(e.w, ipc.WithSchema(schema))
+	return nil
+}
+
+func (e *exporter) flush() error {
+	if len(e.batch) == 0 {
+		return nil
+	}
+	if e.writer == nil {
+		if err := e.open(inferSchema(e.batch)); err != nil {
+			return err
+		}
+	}
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, e.schema)
+	defer b.Release()
+	for _, rec := range e.batch {
+		for i, field := range e.schema.Fields() {
+			if err := appendValue(b.Field(i), rec, field.Name); err != nil {
+				return err
+			}
+		}
+	}
+	batch := b.NewRecord()
+	defer batch.Release()
+	if err := e.writer.Write(batch); err != nil {
+		return err
+	}
+	e.rows += int64(len(e.batch))
+	e.batch = e.batch[:0]
+	return nil
+}
+
+// inferSchema takes the columns of the first record and maps their
+// database types, see arrowType.
+func inferSchema(recs []spcdb.Record) *arrow.Schema {
+	cols := recs[0].Columns()
+	fields := make([]arrow.Field, len(cols))
+	for i, col := range cols {
+		fields[i] = arrow.Field{Name: col.Name, Type: arrowType(col.Type), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// arrowType maps a database type by its spcdb.ColumnFamily; numerics,
+// times of day and unknown types are written as text.
+func arrowType(dbType string) arrow.DataType {
+	t := strings.ToLower(dbType)
+	switch spcdb.ColumnFamily(dbType) {
+	case spcdb.FamilyInt:
+		if strings.Contains(t, "unsigned") {
+			return arrow.PrimitiveTypes.Uint64
+		}
+		return arrow.PrimitiveTypes.Int64
+	case spcdb.FamilyFloat:
+		return arrow.PrimitiveTypes.Float64
+	case spcdb.FamilyBool:
+		return arrow.FixedWidthTypes.Boolean
+	case spcdb.FamilyTime:
+		if strings.HasPrefix(t, "time") && !strings.HasPrefix(t, "timestamp") {
+			return arrow.BinaryTypes.String
+		}
+		return arrow.FixedWidthTypes.Timestamp_us
+	case spcdb.FamilyBytes:
+		return arrow.BinaryTypes.Binary
+	}
+	return arrow.BinaryTypes.String
+}
+
+func appendValue(b array.Builder, rec spcdb.Record, key string) error {
+	value, present, null := rec.GetOk(key)
+	if !present || null {
+		b.AppendNull()
+		return nil
+	}
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		b.Append(rec.GetInString(key))
+		return nil
+	case *array.Int64Builder:
+		if v, ok := toInt64(value); ok {
+			b.Append(v)
+			return nil
+		}
+		if v, err := strconv.ParseInt(textOf(value), 10, 64); err == nil {
+			b.Append(v)
+			return nil
+		}
+	case *array.Uint64Builder:
+		if v, ok := toInt64(value); ok && v >= 0 {
+			b.Append(uint64(v))
+			return nil
+		}
+		if v, ok := value.(uint64); ok {
+			b.Append(v)
+			return nil
+		}
+		if v, ok := value.(uint); ok {
+			b.Append(uint64(v))
+			return nil
+		}
+		if v, err := strconv.ParseUint(textOf(value), 10, 64); err == nil {
+			b.Append(v)
+			return nil
+		}
+	case *array.Float64Builder:
+		switch v := value.(type) {
+		case float64:
+			b.Append(v)
+			return nil
+		case float32:
+			b.Append(float64(v))
+			return nil
+		case *big.Rat:
+			f, _ := v.Float64()
+			b.Append(f)
+			return nil
+		}
+		if v, ok := toInt64(value); ok {
+			b.Append(float64(v))
+			return nil
+		}
+		if v, err := strconv.ParseFloat(textOf(value), 64); err == nil {
+			b.Append(v)
+			return nil
+		}
+	case *array.BooleanBuilder:
+		if v, ok := value.(bool); ok {
+			b.Append(v)
+			return nil
+		}
+		// MySQL booleans are tinyint(1).
+		if v, ok := toInt64(value); ok {
+			b.Append(v != 0)
+			return nil
+		}
+		if v, err := strconv.ParseBool(textOf(value)); err == nil {
+			b.Append(v)
+			return nil
+		}
+	case *array.TimestampBuilder:
+		if v, ok := value.(time.Time); ok {
+			b.Append(arrow.Timestamp(v.UnixMicro()))
+			return nil
+		}
+		for _, layout := range textTimeLayouts {
+			if v, err := time.Parse(layout, textOf(value)); err == nil {
+				b.Append(arrow.Timestamp(v.UnixMicro()))
+				return nil
+			}
+		}
+	case *array.BinaryBuilder:
+		switch v := value.(type) {
+		case []byte:
+			b.Append(v)
+			return nil
+		case string:
+			b.Append([]byte(v))
+			return nil
+		}
+	}
+	return fmt.Errorf("spcdb: Column '%s' exported as %s cannot hold %T", key, b.Type(), value)
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// textTimeLayouts parse the times drivers return as text, e.g. SQLite's.
+var textTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+// textOf is the text of values drivers return unparsed, or "".
+func textOf(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
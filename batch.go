@@ -0,0 +1,120 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BatchOptions tune InsertBatch.
+type BatchOptions struct {
+	// AsyncInsert lets ClickHouse buffer the rows server-side
+	// (async_insert); WaitForAsyncInsert makes it acknowledge them only
+	// once flushed.
+	AsyncInsert        bool
+	WaitForAsyncInsert bool
+	// Settings are further ClickHouse settings of the INSERT.
+	Settings map[string]string
+}
+
+func isClickHouse(driverName string) bool {
+	return driverName == "clickhouse"
+}
+
+// InsertBatch inserts rows, a slice of Records or of structs, in one
+// transaction through a single prepared statement. On ClickHouse this is
+// the driver's batch protocol: rows are buffered and sent as one block on
+// commit. Columns are those of the first row.
+func (db *DB) InsertBatch(table string, rows interface{}, opts *BatchOptions) error {
+	return db.InsertBatchContext(context.Background(), table, rows, opts)
+}
+
+func (db *DB) InsertBatchContext(ctx context.Context, table string, rows interface{}, opts *BatchOptions) error {
+	recs, err := batchRecords(db.config(), rows)
+	if err != nil || len(recs) == 0 {
+		return err
+	}
+	cols := writableColumns(db.config(), table, recs[0])
+	query := batchInsertSQL(db.driver, table, cols, opts)
+
+	start := time.Now()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareStmtContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, rec := range recs {
+		args := make([]interface{}, len(cols))
+		for i, col := range cols {
+			args[i] = encodeColumn(db.config(), db.driver, rec, col, rec.Get(col))
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return stmtInfo{q: tx.queryer(), query: query, start: start}.wrap(err)
+	}
+	fireWrite(db, WriteEvent{Op: WriteInsert, Table: table, Keys: recs})
+	return nil
+}
+
+func batchInsertSQL(driverName, table string, cols []string, opts *BatchOptions) string {
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = quoteIdent(driverName, col)
+	}
+	query := "INSERT INTO " + quoteIdent(driverName, table) + " (" + strings.Join(quoted, ", ") + ")"
+	if !isClickHouse(driverName) {
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+		return rebind(driverName, query+" VALUES ("+marks+")")
+	}
+
+	settings := make([]string, 0)
+	if opts != nil {
+		if opts.AsyncInsert {
+			settings = append(settings, "async_insert = 1")
+			wait := "0"
+			if opts.WaitForAsyncInsert {
+				wait = "1"
+			}
+			settings = append(settings, "wait_for_async_insert = "+wait)
+		}
+		keys := make([]string, 0, len(opts.Settings))
+		for key := range opts.Settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			settings = append(settings, key+" = "+opts.Settings[key])
+		}
+	}
+	if len(settings) > 0 {
+		query += " SETTINGS " + strings.Join(settings, ", ")
+	}
+	return query
+}
+
+func batchRecords(conf *config, rows interface{}) ([]Record, error) {
+	if recs, ok := rows.([]Record); ok {
+		return recs, nil
+	}
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("spcdb: InsertBatch expects a slice, got %T", rows)
+	}
+	recs := make([]Record, v.Len())
+	for i := range recs {
+		recs[i] = newRecordOf(conf, v.Index(i).Interface())
+	}
+	return recs, nil
+}
@@ -0,0 +1,31 @@
+package spcdb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+func (db *DB) markBroken() {
+	atomic.StoreInt32(&db.broken, 1)
+}
+
+func (db *DB) isBroken() bool {
+	return atomic.LoadInt32(&db.broken) != 0
+}
+
+// noteBadConn marks the DB behind q broken on errors database/sql can't
+// recover from by itself.
+func noteBadConn(q sqlQueryer, err error) {
+	if err == nil || !isBadConn(err) {
+		return
+	}
+	if h, ok := q.(handle); ok && h.owner != nil {
+		h.owner.markBroken()
+	}
+}
+
+func isBadConn(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || strings.Contains(err.Error(), "sql: database is closed")
+}
@@ -0,0 +1,588 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SelectBuilder assembles a SELECT statement. Conditions are written with
+// '?' placeholders which are rewritten for the target driver on render.
+type SelectBuilder struct {
+	columns  []string
+	table    string
+	where    []string
+	args     []interface{}
+	orders   []string
+	limit    int
+	offset   int
+	unscoped bool
+	// asOf, when set, reads the history table at that time.
+	asOf *time.Time
+	// err is the first error of building the conditions.
+	err error
+	// conf is the config of the DB the builder last ran on; nil renders
+	// with the defaults.
+	conf *config
+}
+
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+func (b *SelectBuilder) OrderBy(orders ...string) *SelectBuilder {
+	b.orders = append(b.orders, orders...)
+	return b
+}
+
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+// Unscoped includes the soft deleted rows of the table.
+func (b *SelectBuilder) Unscoped() *SelectBuilder {
+	b.unscoped = true
+	return b
+}
+
+func (b *SelectBuilder) SQL(driverName string) (string, []interface{}) {
+	var buf strings.Builder
+	buf.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		buf.WriteString("*")
+	} else {
+		buf.WriteString(strings.Join(b.columns, ", "))
+	}
+	table, where, args := b.table, b.where, b.args
+	if b.asOf != nil {
+		table += HistorySuffix
+		cond, asOfArgs := AsOf(*b.asOf)
+		where = append(append([]string{}, where...), cond)
+		args = append(append([]interface{}{}, args...), asOfArgs...)
+	}
+	if table != "" {
+		buf.WriteString(" FROM ")
+		buf.WriteString(table)
+	}
+	if col := softDeleteColumn(table, b.conf.orDefault().tagName); col != "" && !b.unscoped {
+		where = append([]string{quoteIdent(driverName, col) + " IS NULL"}, where...)
+	}
+	writeWhere(&buf, where)
+	if len(b.orders) > 0 {
+		buf.WriteString(" ORDER BY ")
+		buf.WriteString(strings.Join(b.orders, ", "))
+	}
+	query := DialectFor(driverName).Paging(buf.String(), len(b.orders) > 0, b.limit, b.offset)
+	return rebind(driverName, query), args
+}
+
+func (b *SelectBuilder) QueryRecords(q Queryer) ([]Record, error) {
+	query, args, err := b.recordSQL(q)
+	if err != nil {
+		return nil, err
+	}
+	return q.QueryRecords(query, args...)
+}
+
+func (b *SelectBuilder) QueryRecord(q Queryer) (Record, error) {
+	query, args, err := b.recordSQL(q)
+	if err != nil {
+		return nil, err
+	}
+	return q.QueryRecord(query, args...)
+}
+
+// recordSQL expands "SELECT *" when the table has excluded columns.
+func (b *SelectBuilder) recordSQL(q Queryer) (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	b.conf = configOf(q)
+	if len(b.columns) > 0 || b.table == "" || !b.conf.hasExclusions(b.table) {
+		query, args := b.SQL(q.DriverName())
+		return query, args, nil
+	}
+	cols, err := selectableColumns(q, b.table)
+	if err != nil {
+		return "", nil, err
+	}
+	expanded := *b
+	expanded.columns = cols
+	query, args := expanded.SQL(q.DriverName())
+	return query, args, nil
+}
+
+func (b *SelectBuilder) QueryModel(q Queryer, model interface{}) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.conf = configOf(q)
+	query, args := b.SQL(q.DriverName())
+	return q.QueryModel(query, model, args...)
+}
+
+type InsertBuilder struct {
+	table    string
+	values   Record
+	model    interface{}
+	conflict []string
+	stamps   map[string]interface{}
+	// omit are columns left to the database, e.g. a serial key.
+	omit []string
+	// conf is the config of the DB the builder last ran on; nil renders
+	// with the defaults.
+	conf *config
+}
+
+func Insert(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+func (b *InsertBuilder) Values(rec Record) *InsertBuilder {
+	b.values = rec
+	return b
+}
+
+// Model takes the values from a struct; ids of fields tagged "genid" are
+// generated on Exec.
+func (b *InsertBuilder) Model(model interface{}) *InsertBuilder {
+	b.model = model
+	b.values = newRecordOf(b.conf.orDefault(), model)
+	return b
+}
+
+// Omit leaves the columns out of the insert for the database to fill,
+// e.g. a serial key read back with ExecReturningID.
+func (b *InsertBuilder) Omit(columns ...string) *InsertBuilder {
+	b.omit = append(b.omit, columns...)
+	return b
+}
+
+// OnConflict turns the insert into an upsert updating the other columns
+// of the row with the same keys, where the dialect supports it.
+func (b *InsertBuilder) OnConflict(keys ...string) *InsertBuilder {
+	b.conflict = keys
+	return b
+}
+
+func (b *InsertBuilder) SQL(driverName string) (string, []interface{}) {
+	stamps := b.stamps
+	if stamps == nil {
+		stamps = autoTimestamps(b.table, b.model, b.values, true, b.conf.orDefault().tagName)
+	}
+	cols := stampedColumns(writableColumns(b.conf, b.table, b.values), stamps)
+	for i := 0; i < len(cols); i++ {
+		if containsString(b.omit, cols[i]) {
+			cols = append(cols[:i], cols[i+1:]...)
+			i--
+		}
+	}
+	args := make([]interface{}, 0, len(cols))
+	marks := make([]string, len(cols))
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		marks[i] = "?"
+		quoted[i] = quoteIdent(driverName, col)
+		value, stamped := stamps[col]
+		if !stamped {
+			value = b.values.Get(col)
+		}
+		if _, ok := value.(serverNow); ok {
+			marks[i] = "CURRENT_TIMESTAMP"
+			continue
+		}
+		args = append(args, encodeColumn(b.conf, driverName, b.values, col, value))
+	}
+	query := "INSERT INTO " + quoteIdent(driverName, b.table) + " (" + strings.Join(quoted, ", ") +
+		") VALUES (" + strings.Join(marks, ", ") + ")"
+	if len(b.conflict) > 0 {
+		if clause, ok := DialectFor(driverName).Upsert(b.conflict, cols); ok {
+			query += clause
+		}
+	}
+	return rebind(driverName, query), args
+}
+
+func (b *InsertBuilder) Exec(q Queryer) (sql.Result, error) {
+	if err := b.prepare(q); err != nil {
+		return nil, err
+	}
+	defer func() { b.stamps = nil }()
+	query, args := b.SQL(q.DriverName())
+	res, err := execQueryer(q, query, args...)
+	if err == nil {
+		fireWrite(q, WriteEvent{Op: WriteInsert, Table: b.table, Keys: []Record{b.values}})
+	}
+	return res, err
+}
+
+// ExecReturningID inserts the row and returns the generated value of the
+// integer column, see DB.ExecReturningID.
+func (b *InsertBuilder) ExecReturningID(q Queryer, column string) (int64, error) {
+	if err := b.prepare(q); err != nil {
+		return 0, err
+	}
+	defer func() { b.stamps = nil }()
+	query, args := b.SQL(q.DriverName())
+	var id int64
+	var err error
+	if h, ok := q.(interface{ queryer() sqlQueryer }); ok {
+		id, err = execReturningID(context.Background(), h.queryer(), q.DriverName(), query, column, args...)
+	} else {
+		var res sql.Result
+		if res, err = q.Exec(query, args...); err == nil {
+			id, err = res.LastInsertId()
+		}
+	}
+	if err == nil {
+		fireWrite(q, WriteEvent{Op: WriteInsert, Table: b.table, Keys: []Record{b.values}})
+	}
+	return id, err
+}
+
+// prepare generates ids and timestamps before the insert.
+func (b *InsertBuilder) prepare(q Queryer) error {
+	b.conf = configOf(q)
+	if len(b.conflict) > 0 {
+		if _, ok := DialectFor(q.DriverName()).Upsert(b.conflict, nil); !ok {
+			return fmt.Errorf("spcdb: No upsert for driver '%s'", q.DriverName())
+		}
+	}
+	if b.model != nil {
+		if err := GenerateIDs(q, b.table, b.model); err != nil {
+			return err
+		}
+		b.values = newRecordOf(b.conf, b.model)
+	}
+	b.stamps = autoTimestamps(b.table, b.model, b.values, true, b.conf.tagName)
+	if b.model != nil {
+		stampModel(b.model, b.stamps, b.conf.tagName)
+	}
+	return nil
+}
+
+type UpdateBuilder struct {
+	table  string
+	values Record
+	model  interface{}
+	where  []string
+	args   []interface{}
+	stamps map[string]interface{}
+	// keys are the primary key columns, left out of SET.
+	keys []string
+	// conf is the config of the DB the builder last ran on; nil renders
+	// with the defaults.
+	conf *config
+}
+
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// Set takes the values to write; of a *TrackedRecord only the changed
+// ones, and the record is reset once they are written.
+func (b *UpdateBuilder) Set(rec Record) *UpdateBuilder {
+	b.values = rec
+	return b
+}
+
+// written are the values to write.
+func (b *UpdateBuilder) written() Record {
+	if tracked, ok := b.values.(*TrackedRecord); ok {
+		return tracked.ChangedRecord()
+	}
+	return b.values
+}
+
+// Model takes the values from a struct; its version field, if the table
+// has one, is incremented on a successful Exec.
+func (b *UpdateBuilder) Model(model interface{}) *UpdateBuilder {
+	b.model = model
+	b.values = newRecordOf(b.conf.orDefault(), model)
+	return b
+}
+
+func (b *UpdateBuilder) Where(cond string, args ...interface{}) *UpdateBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// lockVersion returns the version column of the table when the values
+// carry it.
+func (b *UpdateBuilder) lockVersion() string {
+	col := versionColumn(b.table, b.conf.orDefault().tagName)
+	if col == "" || b.values == nil || b.values.GetRaw(col) == nil {
+		return ""
+	}
+	return col
+}
+
+// SQL renders an optimistic locking update, bumping the version and
+// matching the current one, when the table has a version column.
+func (b *UpdateBuilder) SQL(driverName string) (string, []interface{}) {
+	version := b.lockVersion()
+	stamps := b.stamps
+	if stamps == nil {
+		stamps = autoTimestamps(b.table, b.model, b.values, false, b.conf.orDefault().tagName)
+	}
+	created, _ := timestampColumns(b.table, b.model, b.conf.orDefault().tagName)
+	cols := stampedColumns(writableColumns(b.conf, b.table, b.written()), stamps)
+	args := make([]interface{}, 0, len(cols)+len(b.args)+1)
+	sets := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if col == version || col == created || containsString(b.keys, col) {
+			continue
+		}
+		value, stamped := stamps[col]
+		if !stamped {
+			value = b.values.Get(col)
+		}
+		if _, ok := value.(serverNow); ok {
+			sets = append(sets, quoteIdent(driverName, col)+" = CURRENT_TIMESTAMP")
+			continue
+		}
+		args = append(args, encodeColumn(b.conf, driverName, b.values, col, value))
+		sets = append(sets, quoteIdent(driverName, col)+" = ?")
+	}
+	where := b.where
+	args = append(args, b.args...)
+	if version != "" {
+		quoted := quoteIdent(driverName, version)
+		sets = append(sets, quoted+" = "+quoted+" + 1")
+		where = append(append([]string{}, where...), quoted+" = ?")
+		args = append(args, encodeValue(b.values.Get(version)))
+	}
+
+	var buf strings.Builder
+	buf.WriteString("UPDATE " + quoteIdent(driverName, b.table) + " SET " + strings.Join(sets, ", "))
+	writeWhere(&buf, where)
+	return rebind(driverName, buf.String()), args
+}
+
+func (b *UpdateBuilder) Exec(q Queryer) (sql.Result, error) {
+	tracked, _ := b.values.(*TrackedRecord)
+	if tracked != nil && len(tracked.Changed()) == 0 {
+		return driver.RowsAffected(0), nil
+	}
+	b.conf = configOf(q)
+	if b.model != nil {
+		b.values = newRecordOf(b.conf, b.model)
+	}
+	b.stamps = autoTimestamps(b.table, b.model, b.values, false, b.conf.tagName)
+	defer func() { b.stamps = nil }()
+	if b.model != nil {
+		stampModel(b.model, b.stamps, b.conf.tagName)
+	}
+	query, args := b.SQL(q.DriverName())
+	res, err := execQueryer(q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if version := b.lockVersion(); version != "" {
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, &StaleRecordError{Table: b.table, Version: b.values.Get(version)}
+		}
+		if b.model != nil {
+			bumpVersion(b.model, version, b.conf.tagName)
+		}
+		if tracked != nil {
+			if v := reflect.ValueOf(tracked.Get(version)); v.IsValid() && isIntKind(v.Kind()) {
+				tracked.Set(version, v.Convert(reflect.TypeOf(int64(0))).Int()+1)
+			}
+		}
+	}
+	if tracked != nil {
+		tracked.Reset()
+	}
+	fireWrite(q, WriteEvent{Op: WriteUpdate, Table: b.table, Keys: whereKeys(b.where, b.args)})
+	return res, nil
+}
+
+// bumpVersion increments the integer field of the column in the model.
+func bumpVersion(model interface{}, column, tag string) {
+	val := reflect.ValueOf(model)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return
+	}
+	val = val.Elem()
+	info, found := structInfoFor(val.Type(), tag).byName[column]
+	if !found {
+		return
+	}
+	field, ok := fieldByIndex(val, info.index)
+	if !ok || !field.CanSet() {
+		return
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(field.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(field.Uint() + 1)
+	}
+}
+
+type DeleteBuilder struct {
+	table    string
+	where    []string
+	args     []interface{}
+	unscoped bool
+	// conf is the config of the DB the builder last ran on; nil renders
+	// with the defaults.
+	conf *config
+}
+
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+func (b *DeleteBuilder) Where(cond string, args ...interface{}) *DeleteBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// Unscoped removes the rows of a soft deleting table for good.
+func (b *DeleteBuilder) Unscoped() *DeleteBuilder {
+	b.unscoped = true
+	return b
+}
+
+// SQL renders an UPDATE setting the soft delete column for tables with
+// one, skipping rows that are already deleted.
+func (b *DeleteBuilder) SQL(driverName string) (string, []interface{}) {
+	var buf strings.Builder
+	col := softDeleteColumn(b.table, b.conf.orDefault().tagName)
+	if col == "" || b.unscoped {
+		buf.WriteString("DELETE FROM " + quoteIdent(driverName, b.table))
+		writeWhere(&buf, b.where)
+		return rebind(driverName, buf.String()), b.args
+	}
+	col = quoteIdent(driverName, col)
+	buf.WriteString("UPDATE " + quoteIdent(driverName, b.table) + " SET " + col + " = ?")
+	writeWhere(&buf, append([]string{col + " IS NULL"}, b.where...))
+	return rebind(driverName, buf.String()), append([]interface{}{time.Now()}, b.args...)
+}
+
+func (b *DeleteBuilder) Exec(q Queryer) (sql.Result, error) {
+	b.conf = configOf(q)
+	query, args := b.SQL(q.DriverName())
+	res, err := execQueryer(q, query, args...)
+	if err == nil {
+		fireWrite(q, WriteEvent{Op: WriteDelete, Table: b.table, Keys: whereKeys(b.where, b.args)})
+	}
+	return res, err
+}
+
+// execQueryer runs through the helpers' statement path, hooks included,
+// when q is one of ours.
+func execQueryer(q Queryer, query string, args ...interface{}) (sql.Result, error) {
+	if h, ok := q.(interface{ queryer() sqlQueryer }); ok {
+		return runExec(context.Background(), h.queryer(), query, args...)
+	}
+	return q.Exec(query, args...)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeWhere(buf *strings.Builder, where []string) {
+	if len(where) == 0 {
+		return
+	}
+	buf.WriteString(" WHERE ")
+	for i, cond := range where {
+		if i > 0 {
+			buf.WriteString(" AND ")
+		}
+		if len(where) > 1 {
+			buf.WriteString("(" + cond + ")")
+		} else {
+			buf.WriteString(cond)
+		}
+	}
+}
+
+// writableColumns lists the sorted, not excluded, columns of rec.
+func writableColumns(conf *config, table string, rec Record) []string {
+	cols := make([]string, 0)
+	if rec != nil {
+		rec.Each(func(key string, _ reflect.Value) {
+			if !conf.isExcluded(table, key) {
+				cols = append(cols, key)
+			}
+		})
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func isPostgres(driverName string) bool {
+	_, ok := DialectFor(driverName).(postgresDialect)
+	return ok
+}
+
+func quoteIdent(driverName, name string) string {
+	return DialectFor(driverName).QuoteIdent(name)
+}
+
+// rebind replaces '?' placeholders outside of quoted literals with the
+// form used by the driver's dialect.
+func rebind(driverName, query string) string {
+	d := DialectFor(driverName)
+	if d.Placeholder(1) == "?" {
+		return query
+	}
+	var buf strings.Builder
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			buf.WriteString(d.Placeholder(n))
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
@@ -0,0 +1,139 @@
+package spcdb
+
+import (
+	"reflect"
+)
+
+// Clone copies the record. Values read from a struct no longer alias its
+// fields, and slices and maps are copied one level deep; what pointers
+// point to is still shared.
+func (r *record) Clone() Record {
+	if r == nil {
+		return nil
+	}
+	return r.clone()
+}
+
+func (r *record) clone() *record {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	ret := &record{raw: make(map[string]reflect.Value, len(r.raw)), cols: r.cols, conf: r.conf}
+	for key, value := range r.raw {
+		ret.raw[key] = cloneValue(value)
+	}
+	return ret
+}
+
+// Freeze returns a read-only copy of the record, safe to share between
+// goroutines. Its Merge panics.
+func (r *record) Freeze() Record {
+	if r == nil {
+		return nil
+	}
+	return frozenRecord{r.clone()}
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		ret := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(ret, v)
+		return ret
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		ret := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			ret.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return ret
+	}
+	if !v.CanInterface() {
+		return v
+	}
+	// Going through an interface drops the addressability of struct
+	// fields, so the copy cannot be set through.
+	return reflect.ValueOf(v.Interface())
+}
+
+// frozenRecord hands out copies of slices and maps, so that they cannot be
+// changed in place either.
+type frozenRecord struct {
+	r *record
+}
+
+func (f frozenRecord) Get(key string) interface{} {
+	if raw := f.GetRaw(key); raw != nil && raw.IsValid() {
+		return raw.Interface()
+	}
+	return nil
+}
+
+func (f frozenRecord) GetRaw(key string) *reflect.Value {
+	raw := f.r.GetRaw(key)
+	if raw == nil {
+		return nil
+	}
+	v := cloneValue(*raw)
+	return &v
+}
+
+func (f frozenRecord) GetInString(key string) string {
+	return f.r.GetInString(key)
+}
+
+func (f frozenRecord) GetJSON(key string, dest interface{}) error {
+	return f.r.GetJSON(key, dest)
+}
+
+func (f frozenRecord) Each(fn func(key string, value reflect.Value)) {
+	f.r.Each(func(key string, value reflect.Value) {
+		fn(key, cloneValue(value))
+	})
+}
+
+func (f frozenRecord) Range(fn func(key string, value reflect.Value) bool) {
+	f.r.Range(func(key string, value reflect.Value) bool {
+		return fn(key, cloneValue(value))
+	})
+}
+
+func (f frozenRecord) Merge(Record) {
+	panic("spcdb: Merge into a frozen Record")
+}
+
+func (f frozenRecord) Model(dst interface{}) error {
+	return f.r.Clone().Model(dst)
+}
+
+func (f frozenRecord) ModelWith(dst interface{}, opts DecodeOptions) error {
+	return f.r.Clone().ModelWith(dst, opts)
+}
+
+func (f frozenRecord) Keys() []string {
+	return f.r.Keys()
+}
+
+func (f frozenRecord) Len() int {
+	return f.r.Len()
+}
+
+func (f frozenRecord) Clone() Record {
+	return f.r.Clone()
+}
+
+func (f frozenRecord) Freeze() Record {
+	return f
+}
+
+func (f frozenRecord) String() string {
+	return f.r.String()
+}
@@ -0,0 +1,56 @@
+// Command spcdb-gen writes Go model structs for database tables.
+//
+//	spcdb-gen -driver postgres -dsn "$DSN" -package models -o models/tables.go
+//
+// No SQL driver is linked in; the drivers spcdb-gen can open are the ones
+// imported below.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenchik/spcdb"
+	"github.com/jenchik/spcdb/gen"
+	_ "github.com/jenchik/spcdb/pq"
+)
+
+func main() {
+	driver := flag.String("driver", "postgres", "database/sql driver name")
+	dsn := flag.String("dsn", os.Getenv("SPCDB_DSN"), "data source name, $SPCDB_DSN by default")
+	pkg := flag.String("package", "models", "package of the generated file")
+	tables := flag.String("tables", "", "comma separated tables, all when empty")
+	helpers := flag.Bool("helpers", false, "generate Find and Insert helpers")
+	output := flag.String("o", "", "output file, stdout when empty")
+	flag.Parse()
+
+	if err := run(*driver, *dsn, *pkg, *tables, *helpers, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "spcdb-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, pkg, tables string, helpers bool, output string) error {
+	db, err := spcdb.Open(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	opts := gen.Options{Package: pkg, Helpers: helpers}
+	if tables != "" {
+		opts.Tables = strings.Split(tables, ",")
+	}
+	src, err := gen.Generate(context.Background(), db, opts)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(output, src, 0644)
+}
@@ -0,0 +1,119 @@
+package spcdb
+
+import "time"
+
+const (
+	// DefaultTagName is the struct tag naming the column of a model field,
+	// unless Options.TagName says otherwise.
+	DefaultTagName = "mapstructure"
+	// DefaultTimeFormat formats times in GetInString and is the first
+	// layout tried when decoding a string into a time.Time field.
+	DefaultTimeFormat = "2006-01-02 15:04:05"
+)
+
+// config holds the settings that used to be package globals, per DB, so
+// that libraries sharing spcdb in one process keep their own. Unset
+// fields fall back to the package globals, read when used.
+type config struct {
+	tagName      string
+	timeFormat   string
+	timeLayouts  []string
+	timeLocation *time.Location
+
+	strictDecode    *bool
+	decodeJSON      *bool
+	keyOrder        *KeyOrder
+	keyNormalizer   func(key string) string
+	cursorFetchSize int
+	// hstoreColumns and excluded add to HstoreColumns and ExcludeColumns.
+	hstoreColumns map[string]bool
+	excluded      map[string]map[string]bool
+}
+
+var defaultConfig = &config{
+	tagName:     DefaultTagName,
+	timeFormat:  DefaultTimeFormat,
+	timeLayouts: defaultTimeLayouts,
+}
+
+func (db *DB) config() *config {
+	if db == nil || db.conf == nil {
+		return defaultConfig
+	}
+	return db.conf
+}
+
+// configOf returns the config of the DB q runs on.
+func configOf(q interface{}) *config {
+	if h, ok := q.(interface{ queryer() sqlQueryer }); ok {
+		q = h.queryer()
+	}
+	if h, ok := q.(handle); ok {
+		return h.owner.config()
+	}
+	return defaultConfig
+}
+
+// orDefault is c, or the defaults for a nil c.
+func (c *config) orDefault() *config {
+	if c == nil {
+		return defaultConfig
+	}
+	return c
+}
+
+func (c *config) decodeOptions() decodeOptions {
+	return decodeOptions{strict: c.strict(), tagName: c.tagName, conf: c}
+}
+
+func (c *config) strict() bool {
+	if c := c.orDefault(); c.strictDecode != nil {
+		return *c.strictDecode
+	}
+	return StrictDecode
+}
+
+func (c *config) decodesJSON() bool {
+	if c := c.orDefault(); c.decodeJSON != nil {
+		return *c.decodeJSON
+	}
+	return DecodeJSONColumns
+}
+
+func (c *config) recordKeyOrder() KeyOrder {
+	if c := c.orDefault(); c.keyOrder != nil {
+		return *c.keyOrder
+	}
+	return RecordKeyOrder
+}
+
+func (c *config) normalizer() func(key string) string {
+	if c := c.orDefault(); c.keyNormalizer != nil {
+		return c.keyNormalizer
+	}
+	return KeyNormalizer
+}
+
+func (c *config) fetchSize() int {
+	if c := c.orDefault(); c.cursorFetchSize > 0 {
+		return c.cursorFetchSize
+	}
+	return CursorFetchSize
+}
+
+func (c *config) isHstoreColumn(column string) bool {
+	return c.orDefault().hstoreColumns[column] || isHstoreColumn(column)
+}
+
+func (c *config) isExcluded(table, column string) bool {
+	c = c.orDefault()
+	if c.excluded[""][column] || (table != "" && c.excluded[table][column]) {
+		return true
+	}
+	return isExcluded(table, column)
+}
+
+func (c *config) hasExclusions(table string) bool {
+	c = c.orDefault()
+	return len(c.excluded[""]) > 0 || len(c.excluded[table]) > 0 || hasExclusions(table)
+}
@@ -0,0 +1,81 @@
+package spcdb
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+// decodeColumn converts a scanned value according to its database type.
+func decodeColumn(conf *config, driverName, column, dbType string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return value, nil
+	}
+	if isHstore(conf, driverName, column, dbType) {
+		return decodeHstore(value)
+	}
+	if dbType == "" {
+		return value, nil
+	}
+	if dbType == "UUID" {
+		if b, ok := value.([]byte); ok {
+			return string(b), nil
+		}
+		return value, nil
+	}
+	if dbType == "NUMERIC" || dbType == "DECIMAL" {
+		return decodeNumericColumn(value)
+	}
+	if v, ok := decodeDynamicColumn(dbType, value); ok {
+		return v, nil
+	}
+	if conf.decodesJSON() && isJSONType(dbType) {
+		return decodeJSONColumn(value)
+	}
+	if v, ok, err := decodeArray(dbType, value); ok {
+		return v, err
+	}
+	return value, nil
+}
+
+// encodeValue converts values of generated INSERTs and UPDATEs into
+// something the driver can bind.
+func encodeValue(value interface{}) interface{} {
+	if v, ok := encodeNumeric(value); ok {
+		return v
+	}
+	if v, ok := encodeEnum(value); ok {
+		return v
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return value
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Array && v.Len() == 16 && v.Type().Elem().Kind() == reflect.Uint8 {
+		var u UUID
+		reflect.Copy(reflect.ValueOf(&u).Elem(), v)
+		return u.String()
+	}
+	return value
+}
+
+// encodeColumn is encodeValue for the column of rec, writing maps to
+// hstore columns as hstore.
+func encodeColumn(conf *config, driverName string, rec Record, column string, value interface{}) interface{} {
+	m, ok := value.(map[string]string)
+	if !ok || !isHstore(conf, driverName, column, columnType(rec, column)) {
+		return encodeValue(value)
+	}
+	return formatHstore(m)
+}
+
+// columnType is the database type of the column rec was read from.
+func columnType(rec Record, column string) string {
+	if rec == nil {
+		return ""
+	}
+	for _, col := range rec.Columns() {
+		if col.Name == column {
+			return col.Type
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package spcdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ErrUnsupported is returned by CopyTo on drivers unable to copy out.
+var ErrUnsupported = errors.New("spcdb: COPY TO is not supported by the driver")
+
+type CopyFormat int
+
+const (
+	CopyCSV CopyFormat = iota
+	// CopyCSVHeader is CSV with a header line of column names.
+	CopyCSVHeader
+	CopyText
+	CopyBinary
+)
+
+// CopyStatement wraps the query into COPY ... TO STDOUT in the format.
+func CopyStatement(query string, format CopyFormat) string {
+	query = "COPY (" + strings.TrimRight(strings.TrimSpace(query), ";") + ") TO STDOUT"
+	switch format {
+	case CopyCSVHeader:
+		return query + " WITH (FORMAT csv, HEADER true)"
+	case CopyText:
+		return query + " WITH (FORMAT text)"
+	case CopyBinary:
+		return query + " WITH (FORMAT binary)"
+	}
+	return query + " WITH (FORMAT csv)"
+}
+
+// CopyTo streams the result of the query to w with COPY ... TO STDOUT and
+// returns the number of rows copied. COPY takes no parameters, so the
+// query must be complete. Only the pgx stdlib driver can copy out; other
+// drivers, lib/pq included, get ErrUnsupported.
+func (db *DB) CopyTo(w io.Writer, query string, format CopyFormat) (int64, error) {
+	return db.CopyToContext(context.Background(), w, query, format)
+}
+
+func (db *DB) CopyToContext(ctx context.Context, w io.Writer, query string, format CopyFormat) (int64, error) {
+	if !isPostgres(db.driver) || db.driver == "postgres" {
+		return 0, fmt.Errorf("%w: '%s'", ErrUnsupported, db.driver)
+	}
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	stmt := CopyStatement(query, format)
+	var rows int64
+	err = conn.Raw(func(driverConn interface{}) error {
+		rows, err = copyOut(ctx, driverConn, w, stmt)
+		return err
+	})
+	return rows, err
+}
+
+// copyOut finds PgConn().CopyTo of a pgx stdlib connection without
+// importing pgx.
+func copyOut(ctx context.Context, driverConn interface{}, w io.Writer, stmt string) (int64, error) {
+	v := reflect.ValueOf(driverConn)
+	for _, name := range []string{"Conn", "PgConn"} {
+		m := v.MethodByName(name)
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			return 0, fmt.Errorf("%w: connection %T", ErrUnsupported, driverConn)
+		}
+		v = m.Call(nil)[0]
+	}
+	copyTo := v.MethodByName("CopyTo")
+	if !copyTo.IsValid() || copyTo.Type().NumIn() != 3 || copyTo.Type().NumOut() != 2 {
+		return 0, fmt.Errorf("%w: connection %T", ErrUnsupported, driverConn)
+	}
+	out := copyTo.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(w), reflect.ValueOf(stmt)})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return 0, err
+	}
+	var rows int64
+	if affected := out[0].MethodByName("RowsAffected"); affected.IsValid() {
+		rows = affected.Call(nil)[0].Int()
+	}
+	return rows, nil
+}
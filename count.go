@@ -0,0 +1,44 @@
+package spcdb
+
+import (
+	"context"
+	"strings"
+)
+
+// Count returns the number of rows of the table matching where, which may
+// be empty to count them all.
+func (db *DB) Count(table, where string, args ...interface{}) (int64, error) {
+	return count(context.Background(), db.queryer(), table, where, args...)
+}
+
+// CountQuery returns the number of rows the query returns.
+func (db *DB) CountQuery(query string, args ...interface{}) (int64, error) {
+	return countQuery(context.Background(), db.queryer(), query, args...)
+}
+
+func (tx *Tx) Count(table, where string, args ...interface{}) (int64, error) {
+	return count(context.Background(), tx.queryer(), table, where, args...)
+}
+
+func (tx *Tx) CountQuery(query string, args ...interface{}) (int64, error) {
+	return countQuery(context.Background(), tx.queryer(), query, args...)
+}
+
+func count(ctx context.Context, q sqlQueryer, table, where string, args ...interface{}) (int64, error) {
+	query := "SELECT COUNT(*) FROM " + table
+	if where = strings.TrimSpace(where); where != "" {
+		query += " WHERE " + where
+	}
+	var n int64
+	err := queryScalar(ctx, q, query, &n, args...)
+	return n, err
+}
+
+// countQuery counts in a derived table, without the ORDER BY SQL Server
+// rejects there and nobody needs.
+func countQuery(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (int64, error) {
+	query = stripOrderBy(strings.TrimRight(strings.TrimSpace(query), ";"))
+	var n int64
+	err := queryScalar(ctx, q, "SELECT COUNT(*) FROM ("+query+") AS spcdb_count", &n, args...)
+	return n, err
+}
@@ -0,0 +1,111 @@
+package spcdb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' when zero.
+	Comma rune
+	// Columns select and order the columns; those of the first Record by
+	// default. ReadCSV needs them with NoHeader.
+	Columns []string
+	// NoHeader leaves out, or does not expect, the header line.
+	NoHeader bool
+	// Null stands for NULL and missing values. With the default empty
+	// string, empty fields are read back as NULL.
+	Null string
+}
+
+// WriteCSV writes the records with GetInString formatting.
+func WriteCSV(w io.Writer, recs []Record, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cols := opts.Columns
+	if cols == nil && len(recs) > 0 {
+		for _, col := range recs[0].Columns() {
+			cols = append(cols, col.Name)
+		}
+	}
+	if !opts.NoHeader {
+		if err := cw.Write(cols); err != nil {
+			return err
+		}
+	}
+	line := make([]string, len(cols))
+	for _, rec := range recs {
+		for i, col := range cols {
+			if _, present, null := rec.GetOk(col); !present || null {
+				line[i] = opts.Null
+			} else {
+				line[i] = rec.GetInString(col)
+			}
+		}
+		if err := cw.Write(line); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads records of string values, keyed by the header line or
+// opts.Columns.
+func ReadCSV(r io.Reader, opts *CSVOptions) ([]Record, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cols := opts.Columns
+	if !opts.NoHeader {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return []Record{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cols == nil {
+			cols = header
+		}
+	}
+	if cols == nil {
+		return nil, fmt.Errorf("spcdb: ReadCSV needs Columns without a header")
+	}
+	cr.FieldsPerRecord = len(cols)
+
+	infos := make([]ColumnInfo, len(cols))
+	for i, col := range cols {
+		infos[i] = ColumnInfo{Name: col, Position: i + 1}
+	}
+	recs := make([]Record, 0)
+	for {
+		line, err := cr.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec := &record{raw: make(map[string]reflect.Value, len(cols)), cols: infos}
+		for i, col := range cols {
+			if line[i] == opts.Null {
+				rec.raw[col] = reflect.Value{}
+			} else {
+				rec.raw[col] = reflect.ValueOf(line[i])
+			}
+		}
+		recs = append(recs, rec)
+	}
+}
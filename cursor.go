@@ -0,0 +1,80 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync/atomic"
+)
+
+// CursorFetchSize is the number of rows fetched per round trip when
+// QueryCursor is given no fetch size and the DB's Options none either.
+var CursorFetchSize = 1000
+
+var cursorSeq int64
+
+// QueryCursor streams the rows of the query to fn through a server-side
+// cursor, fetchSize rows at a time, so neither side holds the whole result.
+// Returning an error from fn stops the scan. The cursor lives in its own
+// read-only transaction. Drivers without DECLARE/FETCH fall back to a
+// plain streamed query.
+func (db *DB) QueryCursor(query string, fetchSize int, fn func(Record) error, args ...interface{}) error {
+	return db.QueryCursorContext(context.Background(), query, fetchSize, fn, args...)
+}
+
+func (db *DB) QueryCursorContext(ctx context.Context, query string, fetchSize int, fn func(Record) error, args ...interface{}) error {
+	if !isPostgres(db.driver) {
+		return queryEach(ctx, db.queryer(), query, fn, args...)
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	if err = tx.QueryCursorContext(ctx, query, fetchSize, fn, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// QueryCursor is DB.QueryCursor within the transaction.
+func (tx *Tx) QueryCursor(query string, fetchSize int, fn func(Record) error, args ...interface{}) error {
+	return tx.QueryCursorContext(context.Background(), query, fetchSize, fn, args...)
+}
+
+func (tx *Tx) QueryCursorContext(ctx context.Context, query string, fetchSize int, fn func(Record) error, args ...interface{}) (err error) {
+	q := tx.queryer()
+	if !isPostgres(tx.driver) {
+		return queryEach(ctx, q, query, fn, args...)
+	}
+	if fetchSize < 1 {
+		fetchSize = tx.db.config().fetchSize()
+	}
+	name := "spcdb_cursor_" + strconv.FormatInt(atomic.AddInt64(&cursorSeq, 1), 10)
+	if _, err = runExec(ctx, q, "DECLARE "+name+" NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return err
+	}
+	defer func() {
+		// Failed scans leave the cursor open until the transaction ends
+		// otherwise; the first error is the one worth returning.
+		if err != nil {
+			runExec(ctx, q, "CLOSE "+name)
+		}
+	}()
+	fetch := "FETCH FORWARD " + strconv.Itoa(fetchSize) + " FROM " + name
+	for {
+		n := 0
+		err = queryEach(ctx, q, fetch, func(rec Record) error {
+			n++
+			return fn(rec)
+		})
+		if err != nil {
+			return err
+		}
+		if n < fetchSize {
+			break
+		}
+	}
+	_, err = runExec(ctx, q, "CLOSE "+name)
+	return err
+}
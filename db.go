@@ -1,87 +1,156 @@
 package spcdb
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	_ "github.com/lib/pq"
+	"math/big"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
-	"unicode"
 	"github.com/mitchellh/mapstructure"
 )
 
-var (
-    AttributeName = "mapstructure"
-    TimeFormat = "2006-01-02 15:04:05"
-)
-
 type DBMediator interface {
 	DB() *DB
 }
 
 type DB struct {
 	*sql.DB
+	driver string
+	name   string
+	stmts  *stmtCache
+	conf   *config
+	// broken is set once the connection is known dead, so the pool
+	// replaces it.
+	broken int32
 }
 
 func Open(driverName, dataSourceName string) (*DB, error) {
+	return openDB(driverName, dataSourceName, nil)
+}
+
+// openDB opens the DB, running onOpen on every new connection if set.
+func openDB(driverName, dataSourceName string, onOpen func(ctx context.Context, s *Session) error) (*DB, error) {
 	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
+	if onOpen != nil {
+		// sql.Open connects lazily, so nothing is lost closing it.
+		connector, err := connectorOf(db, dataSourceName)
+		db.Close()
+		if err != nil {
+			return nil, err
+		}
+		db = sql.OpenDB(sessionConnector{connector, driverName, onOpen})
+	}
+
+	ret := &DB{DB: db, driver: driverName, stmts: newStmtCache(StmtCacheSize)}
+	sqliteDefaults(ret, dataSourceName)
+	return ret, nil
+}
 
-	return &DB{db}, nil
+func (db *DB) DriverName() string {
+	return db.driver
 }
 
 type Record interface {
 	Get(key string) interface{}
 	GetRaw(key string) *reflect.Value
 	GetInString(key string) string
+	GetJSON(key string, dest interface{}) error
 	Each(func(key string, value reflect.Value))
+	// Range is Each stopping once fn returns false, like sync.Map.Range.
+	Range(fn func(key string, value reflect.Value) bool)
+	// Merge copies the values of r over the record's; MergeWith lets the
+	// strategy decide.
 	Merge(r Record)
+	MergeWith(r Record, strategy MergeStrategy)
 	Model(dst interface{}) error
+	// ModelWith is Model with the decoder configured per call.
+	ModelWith(dst interface{}, opts DecodeOptions) error
+	// Keys lists the keys in the order of RecordKeyOrder.
+	Keys() []string
+	Len() int
+	// Clone returns an independent copy; Freeze a read-only one.
+	Clone() Record
+	Freeze() Record
+	// Pick returns a new Record of the keys only; Omit one without them.
+	Pick(keys ...string) Record
+	Omit(keys ...string) Record
+	// IsNull tells a NULL value from a zero one; GetOk also tells whether
+	// the key is present at all.
+	IsNull(key string) bool
+	GetOk(key string) (value interface{}, present, null bool)
+	// Columns describes the keys in result column order, with the
+	// database types of query results; other keys follow sorted.
+	Columns() []ColumnInfo
+	// Values form-encodes the record, see NewRecordFromValues.
+	Values() url.Values
+	// MarshalBinary gob-encodes the record; UnmarshalBinary and
+	// UnmarshalRecord decode it.
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
 }
 
+type KeyOrder int
+
+const (
+	// KeyOrderColumns keeps the column order of query results, other keys
+	// follow sorted.
+	KeyOrderColumns KeyOrder = iota
+	KeyOrderSorted
+	// KeyOrderNone is the unspecified order of Go maps, saving the sort.
+	KeyOrderNone
+)
+
+// RecordKeyOrder is the order of Keys, Each and String of Records, unless
+// their DB's Options say otherwise.
+var RecordKeyOrder = KeyOrderColumns
+
 type record struct {
 	raw map[string]reflect.Value
-	m   sync.RWMutex
+	// cols describe the result columns the record was read from.
+	cols []ColumnInfo
+	// conf is the config of the DB the record was read from.
+	conf *config
+	m    sync.RWMutex
 }
 
-func recFromMap(recMap reflect.Value, recType reflect.Type, dst map[string]reflect.Value) {
-    dstVal := reflect.Indirect(reflect.ValueOf(dst))
-	for _, k := range recMap.MapKeys() {
-        dstVal.SetMapIndex(k, recMap.MapIndex(k))
-    }
+func (r *record) config() *config {
+	if r.conf == nil {
+		return defaultConfig
+	}
+	return r.conf
 }
 
-func recFromStruct(valStruct reflect.Value, typeStruct reflect.Type, dst map[string]reflect.Value) {
-	for i := 0; i < typeStruct.NumField(); i++ {
-		typeField := typeStruct.Field(i)
-		if !unicode.IsUpper(rune(typeField.Name[0])) {
-			continue
-		}
-
-		recName := typeField.Tag.Get(AttributeName)
-		if recName == "" {
-			if typeField.Anonymous {
-				continue
-			}
-			recName = typeField.Name
-		} else if recName == "-" {
-			continue
+func recFromMap(recMap reflect.Value, recType reflect.Type, dst map[string]reflect.Value) {
+	for _, k := range recMap.MapKeys() {
+		value := recMap.MapIndex(k)
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
 		}
+		dst[fmt.Sprint(k.Interface())] = value
+	}
+}
 
-		structField := valStruct.Field(i)
-		if !structField.IsValid() || !structField.CanInterface() {
+func recFromStruct(valStruct reflect.Value, typeStruct reflect.Type, dst map[string]reflect.Value, tag string) {
+	for _, field := range structInfoFor(typeStruct, tag).fields {
+		structField, ok := fieldByIndex(valStruct, field.index)
+		if !ok || !structField.IsValid() || !structField.CanInterface() {
 			continue
 		}
-		dst[recName] = structField
+		dst[field.name] = structField
 	}
 }
 
-func recFrom(recObj reflect.Value, dst map[string]reflect.Value) {
+func recFrom(recObj reflect.Value, dst map[string]reflect.Value, tag string) {
 	if recObj.Kind() == reflect.Ptr {
 		recObj = recObj.Elem()
 	}
@@ -89,22 +158,28 @@ func recFrom(recObj reflect.Value, dst map[string]reflect.Value) {
 
 	switch typeObj.Kind() {
 		case reflect.Struct:
-			recFromStruct(recObj, typeObj, dst)
+			recFromStruct(recObj, typeObj, dst, tag)
 		case reflect.Map:
 			recFromMap(recObj, typeObj, dst)
 	}
 }
 
 func NewRecord(mapToObj ...interface{}) Record {
+	return newRecordOf(defaultConfig, mapToObj...)
+}
+
+// newRecordOf is NewRecord reading structs with the tag name of conf.
+func newRecordOf(conf *config, mapToObj ...interface{}) Record {
 	rec := &record{
-		raw: make(map[string]reflect.Value),
+		raw:  make(map[string]reflect.Value),
+		conf: conf,
 	}
 	for _, val := range mapToObj {
 		if val == nil {
 			continue
 		}
 		obj := normalizeValue(reflect.ValueOf(val))
-		recFrom(obj, rec.raw)
+		recFrom(obj, rec.raw, conf.tagName)
 	}
 	return rec
 }
@@ -113,20 +188,67 @@ func (r *record) String() string {
 	if r == nil {
 		return ""
 	}
-    s := make([]string, 0, len(r.raw))
-	for key, _ := range r.raw {
-        s = append(s, fmt.Sprintf("\"%s\":\"%s\"", key, r.GetInString(key)))
+	keys := r.Keys()
+	s := make([]string, 0, len(keys))
+	for _, key := range keys {
+		s = append(s, fmt.Sprintf("\"%s\":\"%s\"", key, r.GetInString(key)))
 	}
 	return "{" + strings.Join(s, ", ") + "}"
 }
 
+func (r *record) Keys() []string {
+	if r == nil {
+		return nil
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.keys()
+}
+
+func (r *record) keys() []string {
+	keys := make([]string, 0, len(r.raw))
+	order := r.config().recordKeyOrder()
+	if order == KeyOrderNone {
+		for key := range r.raw {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+	seen := make(map[string]bool, len(r.cols))
+	if order == KeyOrderColumns {
+		for _, col := range r.cols {
+			if _, ok := r.raw[col.Name]; ok && !seen[col.Name] {
+				seen[col.Name] = true
+				keys = append(keys, col.Name)
+			}
+		}
+	}
+	n := len(keys)
+	for key := range r.raw {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys[n:])
+	return keys
+}
+
+func (r *record) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return len(r.raw)
+}
+
 func (r *record) Get(key string) interface{} {
 	if r == nil {
 		return nil
 	}
 	r.m.RLock()
 	defer r.m.RUnlock()
-	if el, ok := r.raw[key]; ok && el.IsValid() {
+	if el, ok := r.lookup(key); ok && el.IsValid() {
 		return el.Interface()
 	}
 	return nil
@@ -138,7 +260,7 @@ func (r *record) GetRaw(key string) *reflect.Value {
 	}
 	r.m.RLock()
 	defer r.m.RUnlock()
-	el, ok := r.raw[key]
+	el, ok := r.lookup(key)
 	if !ok {
 		return nil
 	}
@@ -151,15 +273,19 @@ func (r *record) GetInString(key string) string {
 	}
 	r.m.RLock()
 	defer r.m.RUnlock()
-	el, ok := r.raw[key]
+	el, ok := r.lookup(key)
 	if !ok || !el.IsValid() {
 		return ""
 	}
+	return stringOf(el.Interface(), r.config())
+}
 
+// stringOf formats a value the way GetInString does.
+func stringOf(value interface{}, conf *config) string {
 	var str string
 	//mapstructure.WeakDecode(el.Interface(), &str)
 	//return str
-	switch s := el.Interface().(type) {
+	switch s := value.(type) {
 	case string:
 		str = s
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
@@ -171,9 +297,21 @@ func (r *record) GetInString(key string) string {
 	case bool:
 		str = strconv.FormatBool(s)
 	case time.Time:
-		str = s.Format(TimeFormat)
+		str = conf.inLocation(s).Format(conf.timeFormat)
+	case *big.Rat:
+		str = formatRat(s)
+	case driver.Valuer:
+		// Custom types read the way they are written.
+		if v, err := s.Value(); err == nil && v != nil {
+			str = stringOf(v, conf)
+		} else if err != nil {
+			str = fmt.Sprintf("%v", value)
+		}
 	default:
-		str = fmt.Sprintf("%v", el.Interface())
+		if name, ok := encodeEnum(value); ok {
+			return name.(string)
+		}
+		str = fmt.Sprintf("%v", value)
 	}
 
 	return str
@@ -185,26 +323,43 @@ func (r *record) Each(fn func(key string, value reflect.Value)) {
 	}
 	r.m.RLock()
 	defer r.m.RUnlock()
-	for key, value := range r.raw {
-		fn(key, value)
+	for _, key := range r.keys() {
+		fn(key, r.raw[key])
 	}
 }
 
-func (r *record) Merge(r2 Record) {
-	if r == nil || r2 == nil {
+func (r *record) Range(fn func(key string, value reflect.Value) bool) {
+	if r == nil {
 		return
 	}
-	r.m.Lock()
-	defer r.m.Unlock()
-	r2.Each(func(key string, value reflect.Value) {
-		r.raw[key] = value
-	})
+	r.m.RLock()
+	defer r.m.RUnlock()
+	for _, key := range r.keys() {
+		if !fn(key, r.raw[key]) {
+			return
+		}
+	}
+}
+
+func (r *record) Merge(r2 Record) {
+	r.MergeWith(r2, MergeOverwrite)
 }
 
 func (r *record) Model(rawVal interface{}) error {
 	if r == nil {
 		return nil
 	}
+	return newModelWith(r.dataMap(), rawVal, r.config().decodeOptions())
+}
+
+func (r *record) ModelWith(dst interface{}, opts DecodeOptions) error {
+	if r == nil {
+		return nil
+	}
+	return newModelWith(r.dataMap(), dst, opts.decodeOptions(r.config()))
+}
+
+func (r *record) dataMap() map[string]interface{} {
 	r.m.RLock()
 	defer r.m.RUnlock()
 
@@ -216,83 +371,379 @@ func (r *record) Model(rawVal interface{}) error {
             dataMap[key] = val.Interface()
         }
     }
-	return newModel(dataMap, rawVal)
+	return dataMap
+}
+
+// Queryer is implemented by *DB and *Tx, so code taking one runs the same
+// inside and outside of a transaction.
+type Queryer interface {
+	sqlQueryer
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	DriverName() string
+	Exists(query string, args ...interface{}) (bool, error)
+	ExistsRecord(query string, args ...interface{}) error
+	QueryModel(query string, model interface{}, args ...interface{}) error
+	QueryModels(query string, dest interface{}, args ...interface{}) error
+	QueryRecords(query string, args ...interface{}) ([]Record, error)
+	QueryRecord(query string, args ...interface{}) (Record, error)
+	ExistsRecordContext(ctx context.Context, query string, args ...interface{}) error
+	QueryModelContext(ctx context.Context, query string, model interface{}, args ...interface{}) error
+	QueryModelsContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error
+	QueryRecordsContext(ctx context.Context, query string, args ...interface{}) ([]Record, error)
+	QueryRecordContext(ctx context.Context, query string, args ...interface{}) (Record, error)
+}
+
+// sqlQueryer is satisfied by *sql.DB, *sql.Tx and *sql.Conn alike.
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 func (db *DB) ExistsRecord(query string, args ...interface{}) error {
-	rows, err := db.Query(query, args...)
+	return existsRecord(context.Background(), db.queryer(), query, args...)
+}
+
+// Exists reports whether the query returns any row.
+func (db *DB) Exists(query string, args ...interface{}) (bool, error) {
+	return exists(context.Background(), db.queryer(), query, args...)
+}
+
+func (db *DB) ExistsContext(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	return exists(ctx, db.queryer(), query, args...)
+}
+
+func (db *DB) QueryModel(query string, model interface{}, args ...interface{}) error {
+	return queryModel(context.Background(), db.queryer(), query, model, args...)
+}
+
+// QueryModels decodes every row into a new element appended to dest,
+// which must be a pointer to a slice of structs or struct pointers.
+func (db *DB) QueryModels(query string, dest interface{}, args ...interface{}) error {
+	return queryModels(context.Background(), db.queryer(), query, dest, args...)
+}
+
+func (db *DB) QueryRecords(query string, args ...interface{}) ([]Record, error) {
+	return queryRecords(context.Background(), db.queryer(), query, args...)
+}
+
+func (db *DB) QueryRecord(query string, args ...interface{}) (Record, error) {
+	return queryRecord(context.Background(), db.queryer(), query, args...)
+}
+
+// QueryEach calls fn for every row as it is read and stops at the first
+// error fn returns.
+func (db *DB) QueryEach(query string, fn func(Record) error, args ...interface{}) error {
+	return queryEach(context.Background(), db.queryer(), query, fn, args...)
+}
+
+func (db *DB) ExistsRecordContext(ctx context.Context, query string, args ...interface{}) error {
+	return existsRecord(ctx, db.queryer(), query, args...)
+}
+
+func (db *DB) QueryModelContext(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	return queryModel(ctx, db.queryer(), query, model, args...)
+}
+
+func (db *DB) QueryModelsContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryModels(ctx, db.queryer(), query, dest, args...)
+}
+
+func (db *DB) QueryRecordsContext(ctx context.Context, query string, args ...interface{}) ([]Record, error) {
+	return queryRecords(ctx, db.queryer(), query, args...)
+}
+
+func (db *DB) QueryRecordContext(ctx context.Context, query string, args ...interface{}) (Record, error) {
+	return queryRecord(ctx, db.queryer(), query, args...)
+}
+
+func (db *DB) QueryEachContext(ctx context.Context, query string, fn func(Record) error, args ...interface{}) error {
+	return queryEach(ctx, db.queryer(), query, fn, args...)
+}
+
+// handle is the sql.DB, sql.Tx or statement cache a helper runs on,
+// together with what the helpers need to know about it.
+type handle struct {
+	sqlQueryer
+	driver string
+	name   string
+	inTx   bool
+	owner  *DB
+	// prepared handles run one statement whatever the query text says.
+	prepared bool
+}
+
+func driverOf(q sqlQueryer) string {
+	if h, ok := q.(handle); ok {
+		return h.driver
+	}
+	return ""
+}
+
+func (h handle) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return h.sqlQueryer.QueryContext(ctx, query, bindArgs(h.driver, args)...)
+}
+
+func (h handle) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return h.sqlQueryer.ExecContext(ctx, query, bindArgs(h.driver, args)...)
+}
+
+// runQuery is the single place every helper sends its statements through.
+func runQuery(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (*resultRows, error) {
+	var rows *resultRows
+	err := retry(ctx, q, false, func() (err error) {
+		rows, err = runQueryOnce(ctx, q, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// runWriteQuery is runQuery for statements writing rows, e.g. INSERT ...
+// RETURNING, retried as runExec retries them.
+func runWriteQuery(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (*resultRows, error) {
+	var rows *resultRows
+	err := retry(ctx, q, true, func() (err error) {
+		rows, err = runQueryOnce(ctx, q, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func runQueryOnce(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (*resultRows, error) {
+	hinted, err := applyDeadlineHint(ctx, q, query)
+	if err != nil {
+		return nil, err
+	}
+	stmt := stmtInfo{q: q, query: query, args: args, start: time.Now()}
+	run := startQuery(ctx, q, query, args)
+	rows, err := q.QueryContext(run.context(ctx), hinted, args...)
+	if err != nil {
+		noteBadConn(q, err)
+		run.end(-1, err)
+		return nil, stmt.wrap(err)
+	}
+	return &resultRows{Rows: rows, run: run, stmt: stmt}, nil
+}
+
+func runExec(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := retry(ctx, q, true, func() (err error) {
+		res, err = runExecOnce(ctx, q, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func runExecOnce(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (sql.Result, error) {
+	hinted, err := applyDeadlineHint(ctx, q, query)
+	if err != nil {
+		return nil, err
+	}
+	stmt := stmtInfo{q: q, query: query, args: args, start: time.Now()}
+	run := startQuery(ctx, q, query, args)
+	res, err := q.ExecContext(run.context(ctx), hinted, args...)
+	noteBadConn(q, err)
+	affected := int64(-1)
+	if err == nil && run != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			affected = n
+		}
+	}
+	run.end(affected, err)
+	return res, stmt.wrap(err)
+}
+
+// resultRows counts the rows read and reports the statement as finished
+// when closed.
+type resultRows struct {
+	*sql.Rows
+	run    *queryRun
+	stmt   stmtInfo
+	count  int64
+	closed bool
+}
+
+func (r *resultRows) Err() error {
+	return r.stmt.wrap(r.Rows.Err())
+}
+
+func (r *resultRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+func (r *resultRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.run.end(r.count, r.Rows.Err())
+	}
+	return err
+}
+
+func existsRecord(ctx context.Context, q sqlQueryer, query string, args ...interface{}) error {
+	rows, err := runQuery(ctx, q, query, args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
 		return sql.ErrNoRows
 	}
 	return nil
 }
 
-func (db *DB) QueryModel(query string, model interface{}, args ...interface{}) error {
-	rows, err := db.Query(query, args...)
+func exists(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (bool, error) {
+	rows, err := runQuery(ctx, q, query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return true, nil
+	}
+	return false, rows.Err()
+}
+
+func queryModel(ctx context.Context, q sqlQueryer, query string, model interface{}, args ...interface{}) error {
+	rows, err := runQuery(ctx, q, query, args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
 		return sql.ErrNoRows
 	}
 
-	cols, err := rows.Columns()
+	cols, err := readColumns(ctx, rows)
 	if err != nil {
 		return err
 	}
-    container, err := newContainer(rows, cols)
-    if err != nil {
-        return err
-    }
-    for key, val := range container {
-        //container[key] = *(*interface{})(&val)
-        container[key] = reflect.ValueOf(val).Elem().Interface()
-    }
-	return newModel(container, model)
+	container, err := newModelMap(rows, cols)
+	if err != nil {
+		return err
+	}
+	return newModelWith(container, model, decodeOptionsFrom(ctx, configOf(q)))
 }
 
-func (db *DB) QueryRecords(query string, args ...interface{}) ([]Record, error) {
-	rows, err := db.Query(query, args...)
+func queryModels(ctx context.Context, q sqlQueryer, query string, dest interface{}, args ...interface{}) error {
+	sliceVal := reflect.ValueOf(dest)
+	if sliceVal.Kind() != reflect.Ptr || sliceVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("spcdb: QueryModels expects a pointer to a slice, got %T", dest)
+	}
+	sliceVal = sliceVal.Elem()
+	elemType := sliceVal.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	rows, err := runQuery(ctx, q, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := readColumns(ctx, rows)
+	if err != nil {
+		return err
+	}
+	opts := decodeOptionsFrom(ctx, configOf(q))
+	for rows.Next() {
+		container, err := newModelMap(rows, cols)
+		if err != nil {
+			return err
+		}
+		elem := reflect.New(elemType)
+		if err = newModelWith(container, elem.Interface(), opts); err != nil {
+			return err
+		}
+		if isPtr {
+			sliceVal.Set(reflect.Append(sliceVal, elem))
+		} else {
+			sliceVal.Set(reflect.Append(sliceVal, elem.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+func queryRecords(ctx context.Context, q sqlQueryer, query string, args ...interface{}) ([]Record, error) {
+	rows, err := runQuery(ctx, q, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	cols, err := rows.Columns()
+	cols, err := readColumns(ctx, rows)
 	if err != nil {
 		return nil, err
 	}
 	ret := make([]Record, 0, 10)
+	conf := configOf(q)
 	for rows.Next() {
-        rec, err := newRecord(rows, cols)
+        rec, err := newRecord(rows, cols, conf)
         if err != nil {
             return nil, err
         }
 		ret = append(ret, rec)
 	}
-	return ret, err
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
-func (db *DB) QueryRecord(query string, args ...interface{}) (Record, error) {
-	rows, err := db.Query(query, args...)
+func queryEach(ctx context.Context, q sqlQueryer, query string, fn func(Record) error, args ...interface{}) error {
+	rows, err := runQuery(ctx, q, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := readColumns(ctx, rows)
+	if err != nil {
+		return err
+	}
+	conf := configOf(q)
+	for rows.Next() {
+		rec, err := newRecord(rows, cols, conf)
+		if err != nil {
+			return err
+		}
+		if err = fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func queryRecord(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (Record, error) {
+	rows, err := runQuery(ctx, q, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
 		return nil, sql.ErrNoRows
 	}
 
-	cols, err := rows.Columns()
+	cols, err := readColumns(ctx, rows)
 	if err != nil {
 		return nil, err
 	}
-	return newRecord(rows, cols)
+	return newRecord(rows, cols, configOf(q))
 }
 
 func normalizeValue(value reflect.Value) reflect.Value {
@@ -303,7 +754,38 @@ func normalizeValue(value reflect.Value) reflect.Value {
 	return value
 }
 
-func newContainer(rows *sql.Rows, cols []string) (map[string]interface{}, error) {
+// columnSet describes the columns of a result set.
+type columnSet struct {
+	names []string
+	// types are the database type names as reported by the driver.
+	types []string
+	// infos are shared by the Records of the result.
+	infos []ColumnInfo
+	driver string
+	conf   *config
+}
+
+func readColumns(ctx context.Context, rows *resultRows) (*columnSet, error) {
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	cols := &columnSet{names: queryColumns(ctx, names), types: make([]string, len(names)), driver: driverOf(rows.stmt.q), conf: configOf(rows.stmt.q)}
+	cols.infos = make([]ColumnInfo, len(names))
+	for i, name := range cols.names {
+		cols.infos[i] = ColumnInfo{Name: name, Position: i + 1}
+	}
+	if colTypes, err := rows.ColumnTypes(); err == nil {
+		for i, ct := range colTypes {
+			cols.types[i] = ct.DatabaseTypeName()
+			cols.infos[i].Type = cols.types[i]
+			cols.infos[i].Nullable, _ = ct.Nullable()
+		}
+	}
+	return cols, nil
+}
+
+func newContainer(rows *resultRows, cols *columnSet) (map[string]interface{}, error) {
 	/*
 		ptrs := make([]interface{}, len(cols))
 		cont := make([]string, len(cols))
@@ -313,35 +795,76 @@ func newContainer(rows *sql.Rows, cols []string) (map[string]interface{}, error)
 		rows.Scan(ptrs...)
         return cont
 	*/
-	pointers := make([]interface{}, len(cols))
-	container := make(map[string]interface{}, len(cols))
+	pointers := make([]interface{}, len(cols.names))
+	container := make(map[string]interface{}, len(cols.names))
 	for i, _ := range pointers {
 		var v interface{}
-		container[cols[i]] = &v
+		container[cols.names[i]] = &v
 		pointers[i] = &v
 	}
-	err := rows.Scan(pointers...)
-    return container, err
+	if err := rows.Scan(pointers...); err != nil {
+		return container, err
+	}
+	for i, ptr := range pointers {
+		v := ptr.(*interface{})
+		decoded, err := decodeColumn(cols.conf, cols.driver, cols.names[i], cols.types[i], *v)
+		if err != nil {
+			return container, fmt.Errorf("spcdb: Column '%s': %s", cols.names[i], err)
+		}
+		*v = decoded
+	}
+	return container, nil
 }
 
-func newRecord(rows *sql.Rows, cols []string) (Record, error) {
+func newModelMap(rows *resultRows, cols *columnSet) (map[string]interface{}, error) {
     container, err := newContainer(rows, cols)
     if err != nil {
         return nil, err
     }
-	rec := record{raw: make(map[string]reflect.Value, len(cols))}
+    for key, val := range container {
+        //container[key] = *(*interface{})(&val)
+        container[key] = reflect.ValueOf(val).Elem().Interface()
+    }
+    return container, nil
+}
+
+func newRecord(rows *resultRows, cols *columnSet, conf *config) (Record, error) {
+    container, err := newContainer(rows, cols)
+    if err != nil {
+        return nil, err
+    }
+	rec := record{raw: make(map[string]reflect.Value, len(cols.names)), cols: cols.infos, conf: conf}
 	for key, value := range container {
+		if conf.isExcluded("", key) {
+			continue
+		}
 		rec.raw[key] = reflect.Indirect(reflect.ValueOf(value)).Elem()
 	}
 	return &rec, nil
 }
 
 func newModel(src, dst interface{}) error {
+	return newModelWith(src, dst, defaultConfig.decodeOptions())
+}
+
+func newModelWith(src, dst interface{}, opts decodeOptions) error {
 	config := &mapstructure.DecoderConfig{
 		Metadata:         nil,
 		Result:           dst,
 		WeaklyTypedInput: true,
-        TagName:          AttributeName,
+		Squash:           true,
+        TagName:          opts.tag(),
+		ZeroFields:       opts.zeroFields,
+	}
+	config.DecodeHook = opts.decodeHook()
+	if m, ok := src.(map[string]interface{}); ok {
+		m = nestColumns(m)
+		markNulls(m)
+		src = m
+	}
+
+	if opts.strict || opts.warnings != nil {
+		config.Metadata = &mapstructure.Metadata{}
 	}
 
 	decoder, err := mapstructure.NewDecoder(config)
@@ -349,5 +872,8 @@ func newModel(src, dst interface{}) error {
 		return err
 	}
 
-	return decoder.Decode(src)
+	if err = decoder.Decode(src); err != nil || config.Metadata == nil {
+		return err
+	}
+	return checkDecoded(dst, config.Metadata, opts)
 }
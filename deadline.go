@@ -0,0 +1,52 @@
+package spcdb
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+type DeadlineHint int
+
+const (
+	DeadlineHintNone DeadlineHint = iota
+	// DeadlineHintComment prefixes statements with a comment carrying the
+	// remaining budget, visible in pg_stat_activity and server logs.
+	// Prepared and cached statements, whose text is fixed, go without.
+	DeadlineHintComment
+	// DeadlineHintLockTimeout sets lock_timeout to the remaining budget.
+	// The setting is scoped with SET LOCAL, so it only applies inside a
+	// transaction; outside of one it falls back to the comment.
+	DeadlineHintLockTimeout
+)
+
+var DeadlineHints = DeadlineHintNone
+
+func applyDeadlineHint(ctx context.Context, q sqlQueryer, query string) (string, error) {
+	if DeadlineHints == DeadlineHintNone {
+		return query, nil
+	}
+	if h, ok := q.(handle); ok {
+		// A varying comment would prepare a new statement every time.
+		if _, cached := h.sqlQueryer.(cachedQueryer); cached || h.prepared {
+			return query, nil
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return query, nil
+	}
+	budget := time.Until(deadline) / time.Millisecond
+	if budget < 1 {
+		budget = 1
+	}
+	ms := strconv.FormatInt(int64(budget), 10)
+
+	if h, ok := q.(handle); ok && h.inTx && DeadlineHints == DeadlineHintLockTimeout {
+		if _, err := q.ExecContext(ctx, "SET LOCAL lock_timeout = '"+ms+"ms'"); err != nil {
+			return "", err
+		}
+		return query, nil
+	}
+	return "/* spcdb deadline_ms=" + ms + " */ " + query, nil
+}
@@ -0,0 +1,307 @@
+package spcdb
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dialect is what the builders and write helpers need to know about the
+// SQL of a database. Dialects are registered per driver name.
+type Dialect interface {
+	// Placeholder is the n-th (from 1) bind parameter.
+	Placeholder(n int) string
+	// QuoteIdent quotes a possibly qualified identifier, or leaves it
+	// alone where quoting would change its meaning.
+	QuoteIdent(name string) string
+	// Paging limits query to limit rows after skipping offset; either may
+	// be zero. ordered tells whether query has an ORDER BY.
+	Paging(query string, ordered bool, limit, offset int) string
+	// ReturningID rewrites an INSERT to return column, or reports false
+	// when the id has to come from LastInsertId.
+	ReturningID(query, column string) (string, bool)
+	// Upsert is the clause following an INSERT's VALUES that updates cols
+	// when a row with the same keys exists; false if not supported.
+	Upsert(keys, cols []string) (string, bool)
+}
+
+// The built-in dialects, for RegisterDriverDefaults.
+var (
+	GenericDialect   Dialect = genericDialect{}
+	PostgresDialect  Dialect = postgresDialect{}
+	MySQLDialect     Dialect = mysqlDialect{}
+	SQLiteDialect    Dialect = sqliteDialect{}
+	SQLServerDialect Dialect = sqlServerDialect{}
+)
+
+var dialects = map[string]Dialect{
+	"postgres":   PostgresDialect,
+	"pgx":        PostgresDialect,
+	"mysql":      MySQLDialect,
+	"sqlite3":    SQLiteDialect,
+	"sqlite":     SQLiteDialect,
+	"sqlserver":  SQLServerDialect,
+	"mssql":      SQLServerDialect,
+	"clickhouse": GenericDialect,
+}
+var mDialects sync.RWMutex
+
+// RegisterDriverDefaults tells spcdb how to talk to the driver registered
+// with database/sql as driverName, e.g. a wrapped or instrumented one.
+// With PostgresDialect, slices are also bound as Postgres arrays.
+func RegisterDriverDefaults(driverName string, d Dialect) {
+	mDialects.Lock()
+	dialects[driverName] = d
+	mDialects.Unlock()
+}
+
+// DialectFor returns the dialect of the driver, a plain '?' and
+// LIMIT/OFFSET one for unknown drivers.
+func DialectFor(driverName string) Dialect {
+	mDialects.RLock()
+	defer mDialects.RUnlock()
+	if d, found := dialects[driverName]; found {
+		return d
+	}
+	return genericDialect{}
+}
+
+type genericDialect struct{}
+
+func (genericDialect) Placeholder(int) string { return "?" }
+
+func (genericDialect) QuoteIdent(name string) string { return name }
+
+func (genericDialect) Paging(query string, _ bool, limit, offset int) string {
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+	if offset > 0 {
+		query += " OFFSET " + strconv.Itoa(offset)
+	}
+	return query
+}
+
+func (genericDialect) ReturningID(string, string) (string, bool) { return "", false }
+
+func (genericDialect) Upsert([]string, []string) (string, bool) { return "", false }
+
+type postgresDialect struct{ genericDialect }
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgresDialect) ReturningID(query, column string) (string, bool) {
+	return returning(query, column), true
+}
+
+func (postgresDialect) Upsert(keys, cols []string) (string, bool) {
+	return onConflict(keys, cols), true
+}
+
+type sqliteDialect struct{ genericDialect }
+
+// Upsert needs SQLite 3.24; RETURNING is left alone as older versions
+// lack it and LastInsertId works everywhere.
+func (sqliteDialect) Upsert(keys, cols []string) (string, bool) {
+	return onConflict(keys, cols), true
+}
+
+type mysqlDialect struct{ genericDialect }
+
+// QuoteIdent quotes every name, as reserved words such as "key" or
+// "order" make popular column names.
+func (mysqlDialect) QuoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// Upsert falls back to a no-op update of the first key when there is
+// nothing else to update, MySQL's way to ignore the duplicate.
+func (d mysqlDialect) Upsert(keys, cols []string) (string, bool) {
+	sets := make([]string, 0, len(cols))
+	for _, col := range nonKeyColumns(keys, cols) {
+		quoted := d.QuoteIdent(col)
+		sets = append(sets, quoted+" = VALUES("+quoted+")")
+	}
+	if len(sets) == 0 && len(keys) > 0 {
+		quoted := d.QuoteIdent(keys[0])
+		sets = append(sets, quoted+" = "+quoted)
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), true
+}
+
+type sqlServerDialect struct{ genericDialect }
+
+func (sqlServerDialect) Placeholder(n int) string { return "@p" + strconv.Itoa(n) }
+
+// Paging uses TOP when there is nothing to skip, OFFSET/FETCH otherwise;
+// the latter needs an ORDER BY.
+func (sqlServerDialect) Paging(query string, ordered bool, limit, offset int) string {
+	if offset <= 0 {
+		if limit <= 0 {
+			return query
+		}
+		upper := strings.ToUpper(query)
+		for _, prefix := range []string{"SELECT DISTINCT ", "SELECT "} {
+			if strings.HasPrefix(upper, prefix) {
+				return query[:len(prefix)] + "TOP " + strconv.Itoa(limit) + " " + query[len(prefix):]
+			}
+		}
+	}
+	if !ordered {
+		query += " ORDER BY (SELECT NULL)"
+	}
+	query += " OFFSET " + strconv.Itoa(offset) + " ROWS"
+	if limit > 0 {
+		query += " FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+	}
+	return query
+}
+
+// ReturningID adds OUTPUT INSERTED, as SQL Server has neither RETURNING nor
+// LastInsertId. The clause goes before the VALUES, DEFAULT VALUES or SELECT
+// of the insert, where one of the query's own is looked for too.
+func (sqlServerDialect) ReturningID(query, column string) (string, bool) {
+	for _, w := range topLevelWords(query) {
+		switch w.text {
+		case "OUTPUT":
+			return query, true
+		case "DEFAULT", "VALUES", "SELECT":
+			at := len(strings.TrimRight(query[:w.pos], " \t\r\n"))
+			return query[:at] + " OUTPUT INSERTED." + column + query[at:], true
+		}
+	}
+	return "", false
+}
+
+// returning adds a RETURNING clause unless the query has one of its own.
+func returning(query, column string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	for _, w := range topLevelWords(query) {
+		if w.text == "RETURNING" {
+			return query
+		}
+	}
+	return query + " RETURNING " + column
+}
+
+func onConflict(keys, cols []string) string {
+	sets := make([]string, 0, len(cols))
+	for _, col := range nonKeyColumns(keys, cols) {
+		sets = append(sets, col+" = EXCLUDED."+col)
+	}
+	clause := " ON CONFLICT (" + strings.Join(keys, ", ") + ")"
+	if len(sets) == 0 {
+		return clause + " DO NOTHING"
+	}
+	return clause + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+func nonKeyColumns(keys, cols []string) []string {
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[key] = true
+	}
+	ret := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if !isKey[col] {
+			ret = append(ret, col)
+		}
+	}
+	return ret
+}
+
+// hasOrderBy tells whether a hand written query sorts its rows.
+func hasOrderBy(query string) bool {
+	return orderByAt(topLevelWords(query)) >= 0
+}
+
+// stripOrderBy drops a trailing ORDER BY of the query, which SQL Server
+// rejects in derived tables; one followed by LIMIT, OFFSET or FETCH
+// decides the rows and stays.
+func stripOrderBy(query string) string {
+	words := topLevelWords(query)
+	at := orderByAt(words)
+	if at < 0 {
+		return query
+	}
+	for _, w := range words[at:] {
+		switch w.text {
+		case "LIMIT", "OFFSET", "FETCH", "FOR":
+			return query
+		}
+	}
+	return strings.TrimSpace(query[:words[at].pos])
+}
+
+// orderByAt is the index of the last ORDER of ORDER BY in words, or -1.
+func orderByAt(words []sqlWord) int {
+	for i := len(words) - 2; i >= 0; i-- {
+		if words[i].text == "ORDER" && words[i+1].text == "BY" {
+			return i
+		}
+	}
+	return -1
+}
+
+// sqlWord is an upper-cased word of a query and its offset.
+type sqlWord struct {
+	text string
+	pos  int
+}
+
+// topLevelWords lists the words of the query outside parentheses, string
+// literals, quoted identifiers and comments, i.e. its own clauses.
+func topLevelWords(query string) []sqlWord {
+	var words []sqlWord
+	depth := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			i++
+			for i < len(query) && query[i] != closing {
+				i++
+			}
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case isWordByte(c):
+			start := i
+			for i < len(query) && isWordByte(query[i]) {
+				i++
+			}
+			if depth == 0 {
+				words = append(words, sqlWord{strings.ToUpper(query[start:i]), start})
+			}
+		default:
+			i++
+		}
+	}
+	return words
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,75 @@
+package spcdb
+
+import "testing"
+
+func TestStripOrderBy(t *testing.T) {
+	tests := []struct{ query, want string }{
+		{"SELECT * FROM t ORDER BY id DESC", "SELECT * FROM t"},
+		{"SELECT * FROM t WHERE id IN (SELECT id FROM u ORDER BY id)", "SELECT * FROM t WHERE id IN (SELECT id FROM u ORDER BY id)"},
+		{"SELECT * FROM t ORDER BY id OFFSET 10 ROWS", "SELECT * FROM t ORDER BY id OFFSET 10 ROWS"},
+		{"SELECT * FROM t ORDER BY id LIMIT 5", "SELECT * FROM t ORDER BY id LIMIT 5"},
+		{"SELECT 'order by' AS x FROM t", "SELECT 'order by' AS x FROM t"},
+	}
+	for _, tt := range tests {
+		if got := stripOrderBy(tt.query); got != tt.want {
+			t.Errorf("stripOrderBy(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPageCountSQLServer(t *testing.T) {
+	db := openRecorderAs(t, "spcdb_rec_mssql")
+	// The recorder has no rows, so the count is the only query sent.
+	db.QueryRecordsPage("SELECT id FROM t WHERE a = @p1 ORDER BY id", 2, 10, 1)
+	want := "SELECT COUNT(*) FROM (SELECT id FROM t WHERE a = @p1) AS spcdb_count"
+	if len(recorder.queries) != 1 || recorder.queries[0] != want {
+		t.Errorf("queries = %q, want %q", recorder.queries, want)
+	}
+}
+
+func TestReturningIDClauses(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{PostgresDialect, "INSERT INTO t (output, returning_at) VALUES ($1, 'returning')",
+			"INSERT INTO t (output, returning_at) VALUES ($1, 'returning') RETURNING id"},
+		{PostgresDialect, "INSERT INTO t (a) VALUES ($1)\nreturning a",
+			"INSERT INTO t (a) VALUES ($1)\nreturning a"},
+		{SQLServerDialect, "INSERT INTO t (output, a) VALUES (@p1, 'output')",
+			"INSERT INTO t (output, a) OUTPUT INSERTED.id VALUES (@p1, 'output')"},
+		{SQLServerDialect, "INSERT INTO t (a)\noutput inserted.a\nVALUES (@p1)",
+			"INSERT INTO t (a)\noutput inserted.a\nVALUES (@p1)"},
+		{SQLServerDialect, "INSERT INTO t DEFAULT VALUES",
+			"INSERT INTO t OUTPUT INSERTED.id DEFAULT VALUES"},
+	}
+	for _, tt := range tests {
+		got, ok := tt.dialect.ReturningID(tt.query, "id")
+		if !ok || got != tt.want {
+			t.Errorf("ReturningID(%q) = %q, %v, want %q", tt.query, got, ok, tt.want)
+		}
+	}
+}
+
+func TestPagingDialects(t *testing.T) {
+	const query = "SELECT id FROM items"
+	tests := []struct {
+		d             Dialect
+		ordered       bool
+		limit, offset int
+		want          string
+	}{
+		{GenericDialect, false, 10, 0, query + " LIMIT 10"},
+		{PostgresDialect, true, 10, 20, query + " LIMIT 10 OFFSET 20"},
+		{MySQLDialect, false, 0, 5, query + " OFFSET 5"},
+		{SQLServerDialect, false, 10, 0, "SELECT TOP 10 id FROM items"},
+		{SQLServerDialect, false, 10, 20, query + " ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{SQLServerDialect, true, 0, 20, query + " OFFSET 20 ROWS"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Paging(query, tt.ordered, tt.limit, tt.offset); got != tt.want {
+			t.Errorf("%T.Paging(%t, %d, %d) = %q, want %q", tt.d, tt.ordered, tt.limit, tt.offset, got, tt.want)
+		}
+	}
+}
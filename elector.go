@@ -0,0 +1,182 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+)
+
+var ElectionInterval = 5 * time.Second
+
+// Elector campaigns for a Postgres session-level advisory lock. The lock
+// lives as long as the session, so the elector holds one dedicated
+// connection taken from the named pool for the whole campaign.
+type Elector struct {
+	connectionName string
+	key            int64
+	interval       time.Duration
+
+	leader bool
+	notify []chan bool
+	db     *DB
+	conn   *sql.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+	m      sync.RWMutex
+}
+
+func NewElector(connectionName string, key int64) *Elector {
+	return &Elector{
+		connectionName: connectionName,
+		key:            key,
+		interval:       ElectionInterval,
+	}
+}
+
+func (e *Elector) SetInterval(interval time.Duration) {
+	e.m.Lock()
+	e.interval = interval
+	e.m.Unlock()
+}
+
+func (e *Elector) IsLeader() bool {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.leader
+}
+
+// Notify returns a channel receiving the new leadership state on every
+// change. Slow readers miss intermediate states but never the latest one.
+func (e *Elector) Notify() <-chan bool {
+	ch := make(chan bool, 1)
+	e.m.Lock()
+	e.notify = append(e.notify, ch)
+	e.m.Unlock()
+	return ch
+}
+
+func (e *Elector) Start() {
+	e.m.Lock()
+	defer e.m.Unlock()
+	if e.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	go e.run(ctx)
+}
+
+// Stop resigns leadership, releases the lock and returns the connection
+// to the pool.
+func (e *Elector) Stop() {
+	e.m.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel = nil
+	e.m.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (e *Elector) run(ctx context.Context) {
+	defer close(e.done)
+	defer e.release()
+	for {
+		e.campaign(ctx)
+
+		e.m.RLock()
+		interval := e.interval
+		e.m.RUnlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (e *Elector) campaign(ctx context.Context) {
+	if e.conn == nil {
+		if err := e.connect(ctx); err != nil {
+			e.setLeader(false)
+			return
+		}
+	}
+
+	if e.IsLeader() {
+		// The lock is only ours while the session is alive.
+		if err := e.conn.PingContext(ctx); err != nil {
+			e.disconnect(true)
+			e.setLeader(false)
+		}
+		return
+	}
+
+	var locked bool
+	err := e.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&locked)
+	if err != nil {
+		e.disconnect(true)
+		locked = false
+	}
+	e.setLeader(locked)
+}
+
+func (e *Elector) connect(ctx context.Context) error {
+	db, err := GetFromPool(e.connectionName)
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.ReturnToPool()
+		return err
+	}
+	e.db, e.conn = db, conn
+	return nil
+}
+
+func (e *Elector) disconnect(broken bool) {
+	if e.conn != nil {
+		if broken {
+			// Keep database/sql from handing the session out again.
+			e.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		e.conn.Close()
+		e.conn = nil
+	}
+	if e.db != nil {
+		e.db.ReturnToPool()
+		e.db = nil
+	}
+}
+
+func (e *Elector) release() {
+	broken := false
+	if e.conn != nil && e.IsLeader() {
+		_, err := e.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", e.key)
+		broken = err != nil
+	}
+	e.disconnect(broken)
+	e.setLeader(false)
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	if e.leader == leader {
+		return
+	}
+	e.leader = leader
+	for _, ch := range e.notify {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- leader
+	}
+}
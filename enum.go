@@ -0,0 +1,91 @@
+package spcdb
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// EnumInt is satisfied by integer enum types, e.g. a `type Status int`
+// with iota constants.
+type EnumInt interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+type enumNames struct {
+	names  map[int64]string
+	values map[string]int64
+}
+
+var int64Type = reflect.TypeOf(int64(0))
+
+var enums = make(map[reflect.Type]*enumNames)
+var mEnums sync.RWMutex
+
+// RegisterEnum maps the values of an integer enum type to the strings the
+// database stores. Model decoding reads the strings into fields of the
+// type, while query arguments and the insert and update helpers write the
+// values as strings.
+func RegisterEnum[T EnumInt](names map[T]string) {
+	e := &enumNames{names: make(map[int64]string, len(names)), values: make(map[string]int64, len(names))}
+	for value, name := range names {
+		n := reflect.ValueOf(value).Convert(int64Type).Int()
+		e.names[n] = name
+		e.values[name] = n
+	}
+	mEnums.Lock()
+	enums[reflect.TypeOf((*T)(nil)).Elem()] = e
+	mEnums.Unlock()
+}
+
+func enumFor(typ reflect.Type) *enumNames {
+	mEnums.RLock()
+	defer mEnums.RUnlock()
+	if len(enums) == 0 {
+		return nil
+	}
+	return enums[typ]
+}
+
+// encodeEnum returns the database string of a registered enum value.
+func encodeEnum(value interface{}) (interface{}, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || !isIntKind(v.Kind()) {
+		return nil, false
+	}
+	e := enumFor(v.Type())
+	if e == nil {
+		return nil, false
+	}
+	n := v.Convert(int64Type).Int()
+	name, found := e.names[n]
+	if !found {
+		return nil, false
+	}
+	return name, true
+}
+
+// enumHook decodes the strings of registered enum types.
+func enumHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	var name string
+	switch v := data.(type) {
+	case string:
+		name = v
+	case []byte:
+		name = string(v)
+	default:
+		return data, nil
+	}
+	e := enumFor(to)
+	if e == nil {
+		return data, nil
+	}
+	n, found := e.values[name]
+	if !found {
+		return nil, fmt.Errorf("spcdb: Unknown %s value '%s'", to, name)
+	}
+	return reflect.ValueOf(n).Convert(to).Interface(), nil
+}
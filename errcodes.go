@@ -0,0 +1,76 @@
+package spcdb
+
+import (
+	"errors"
+	"reflect"
+)
+
+// SQLSTATE codes of the conditions the Is* helpers recognize.
+const (
+	CodeUniqueViolation      = "23505"
+	CodeForeignKeyViolation  = "23503"
+	CodeDeadlockDetected     = "40P01"
+	CodeSerializationFailure = "40001"
+)
+
+// sqlStater is implemented by the errors of drivers reporting SQLSTATE,
+// such as lib/pq and pgx.
+type sqlStater interface {
+	SQLState() string
+}
+
+// ErrorCode returns the SQLSTATE of err, or "" if the driver didn't
+// report one.
+func ErrorCode(err error) string {
+	var stater sqlStater
+	if errors.As(err, &stater) {
+		return stater.SQLState()
+	}
+	if number, ok := mysqlErrorNumber(err); ok {
+		return mysqlCodes[number]
+	}
+	return ""
+}
+
+// mysqlCodes maps the MySQL error numbers of the recognized conditions to
+// their SQLSTATE; MySQL reports a generic state for most of them.
+var mysqlCodes = map[uint64]string{
+	1062: CodeUniqueViolation,
+	1451: CodeForeignKeyViolation,
+	1452: CodeForeignKeyViolation,
+	1213: CodeDeadlockDetected,
+}
+
+// mysqlErrorNumber finds a go-sql-driver/mysql error in the chain without
+// importing the driver.
+func mysqlErrorNumber(err error) (uint64, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct || v.Type().Name() != "MySQLError" {
+			continue
+		}
+		if number := v.FieldByName("Number"); number.IsValid() && number.Kind() == reflect.Uint16 {
+			return number.Uint(), true
+		}
+	}
+	return 0, false
+}
+
+func IsUniqueViolation(err error) bool {
+	return ErrorCode(err) == CodeUniqueViolation
+}
+
+func IsForeignKeyViolation(err error) bool {
+	return ErrorCode(err) == CodeForeignKeyViolation
+}
+
+func IsDeadlock(err error) bool {
+	return ErrorCode(err) == CodeDeadlockDetected
+}
+
+func IsSerializationFailure(err error) bool {
+	return ErrorCode(err) == CodeSerializationFailure
+}
@@ -0,0 +1,85 @@
+package spcdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStaleRecord matches the *StaleRecordError of updates that lost an
+// optimistic locking race.
+var ErrStaleRecord = errors.New("spcdb: Stale record")
+
+// StaleRecordError is returned by an update of a versioned table that
+// found no row with the expected version, i.e. the row was changed or
+// deleted since it was read.
+type StaleRecordError struct {
+	Table   string
+	Version interface{}
+}
+
+func (e *StaleRecordError) Error() string {
+	return fmt.Sprintf("spcdb: Stale record of '%s' at version %v", e.Table, e.Version)
+}
+
+func (e *StaleRecordError) Is(target error) bool {
+	return target == ErrStaleRecord
+}
+
+// ErrorArgsLimit caps how many arguments an Error keeps.
+var ErrorArgsLimit = 10
+
+// Error is returned for statements the database rejected or failed to
+// read, with enough context to tell which statement it was. Args are
+// printable copies of the arguments, redacted as the loggers set with
+// SetLogger redact them.
+type Error struct {
+	Query          string
+	Args           []interface{}
+	ConnectionName string
+	Duration       time.Duration
+	Err            error
+}
+
+func (e *Error) Error() string {
+	name := e.ConnectionName
+	if name == "" {
+		name = "-"
+	}
+	return fmt.Sprintf("spcdb: %v; query '%s' with args %v on '%s' after %s", e.Err, e.Query, e.Args, name, e.Duration)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// stmtInfo remembers a statement for the errors it may still produce.
+type stmtInfo struct {
+	q     sqlQueryer
+	query string
+	args  []interface{}
+	start time.Time
+}
+
+func (s stmtInfo) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	e := &Error{
+		Query:    s.query,
+		Duration: time.Since(s.start),
+		Err:      err,
+	}
+	if h, ok := s.q.(handle); ok {
+		e.ConnectionName = h.name
+	}
+	args := s.args
+	if ErrorArgsLimit >= 0 && len(args) > ErrorArgsLimit {
+		args = args[:ErrorArgsLimit]
+	}
+	e.Args = errorArgs(s.query, args)
+	return e
+}
@@ -0,0 +1,87 @@
+package spcdb
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	excluded     = make(map[string]map[string]bool)
+	tableColumns = make(map[string][]string)
+	mExcluded    sync.RWMutex
+)
+
+// ExcludeColumns keeps the columns out of Records built by the select
+// builder and out of generated INSERTs. An empty table name excludes the
+// columns everywhere, including Records of raw queries.
+func ExcludeColumns(table string, columns ...string) {
+	mExcluded.Lock()
+	defer mExcluded.Unlock()
+	set, found := excluded[table]
+	if !found {
+		set = make(map[string]bool, len(columns))
+		excluded[table] = set
+	}
+	for _, col := range columns {
+		set[col] = true
+	}
+}
+
+func IncludeColumns(table string, columns ...string) {
+	mExcluded.Lock()
+	defer mExcluded.Unlock()
+	for _, col := range columns {
+		delete(excluded[table], col)
+	}
+}
+
+// ForgetTableColumns drops the cached column list of the table, e.g. after
+// a schema change.
+func ForgetTableColumns(table string) {
+	mExcluded.Lock()
+	delete(tableColumns, table)
+	mExcluded.Unlock()
+}
+
+func isExcluded(table, column string) bool {
+	mExcluded.RLock()
+	defer mExcluded.RUnlock()
+	return excluded[""][column] || (table != "" && excluded[table][column])
+}
+
+func hasExclusions(table string) bool {
+	mExcluded.RLock()
+	defer mExcluded.RUnlock()
+	return len(excluded[""]) > 0 || len(excluded[table]) > 0
+}
+
+// selectableColumns lists the columns of the table minus the excluded
+// ones, so that "SELECT *" can be expanded without fetching them.
+func selectableColumns(q sqlQueryer, table string) ([]string, error) {
+	mExcluded.RLock()
+	cols, found := tableColumns[table]
+	mExcluded.RUnlock()
+	if !found {
+		rows, err := q.QueryContext(context.Background(), "SELECT * FROM "+table+" WHERE 1 = 0")
+		if err != nil {
+			return nil, err
+		}
+		cols, err = rows.Columns()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		mExcluded.Lock()
+		tableColumns[table] = cols
+		mExcluded.Unlock()
+	}
+
+	conf := configOf(q)
+	ret := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if !conf.isExcluded(table, col) {
+			ret = append(ret, col)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,72 @@
+package spcdb
+
+import (
+	"context"
+)
+
+// ReturningIDColumn is the column ExecReturningID asks Postgres for when
+// the statement has no RETURNING clause of its own.
+var ReturningIDColumn = "id"
+
+// ExecReturningID runs an INSERT and returns the id of the new row, using
+// what the driver's dialect offers: RETURNING on Postgres, OUTPUT INSERTED
+// on SQL Server, LastInsertId elsewhere.
+func (db *DB) ExecReturningID(query string, args ...interface{}) (int64, error) {
+	return execReturningID(context.Background(), db.queryer(), db.driver, query, ReturningIDColumn, args...)
+}
+
+func (db *DB) ExecReturningIDContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return execReturningID(ctx, db.queryer(), db.driver, query, ReturningIDColumn, args...)
+}
+
+// ExecAffected runs the statement and returns the number of affected rows.
+func (db *DB) ExecAffected(query string, args ...interface{}) (int64, error) {
+	return execAffected(context.Background(), db.queryer(), query, args...)
+}
+
+func (db *DB) ExecAffectedContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return execAffected(ctx, db.queryer(), query, args...)
+}
+
+func (tx *Tx) ExecReturningID(query string, args ...interface{}) (int64, error) {
+	return execReturningID(context.Background(), tx.queryer(), tx.driver, query, ReturningIDColumn, args...)
+}
+
+func (tx *Tx) ExecReturningIDContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return execReturningID(ctx, tx.queryer(), tx.driver, query, ReturningIDColumn, args...)
+}
+
+func (tx *Tx) ExecAffected(query string, args ...interface{}) (int64, error) {
+	return execAffected(context.Background(), tx.queryer(), query, args...)
+}
+
+func (tx *Tx) ExecAffectedContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return execAffected(ctx, tx.queryer(), query, args...)
+}
+
+func execReturningID(ctx context.Context, q sqlQueryer, driverName, query, column string, args ...interface{}) (int64, error) {
+	returning, ok := DialectFor(driverName).ReturningID(query, column)
+	if !ok {
+		res, err := runExec(ctx, q, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	rows, err := runWriteQuery(ctx, q, returning, args...)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = scanScalar(rows, &id)
+	return id, err
+}
+
+func execAffected(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (int64, error) {
+	res, err := runExec(ctx, q, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,50 @@
+package spcdb
+
+import (
+	"net/url"
+	"reflect"
+)
+
+// Values leaves NULLs out and turns slices, []byte aside, into repeated
+// values; the rest is formatted as by GetInString.
+func (r *record) Values() url.Values {
+	ret := make(url.Values)
+	if r == nil {
+		return ret
+	}
+	for _, key := range r.Keys() {
+		value, _, null := r.GetOk(key)
+		if null {
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < v.Len(); i++ {
+				ret.Add(key, stringOf(v.Index(i).Interface(), r.config()))
+			}
+			continue
+		}
+		ret.Set(key, r.GetInString(key))
+	}
+	return ret
+}
+
+func (f frozenRecord) Values() url.Values {
+	return f.r.Values()
+}
+
+// NewRecordFromValues makes a Record of form values: strings, or []string
+// for repeated keys.
+func NewRecordFromValues(values url.Values) Record {
+	m := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		switch len(list) {
+		case 0:
+		case 1:
+			m[key] = list[0]
+		default:
+			m[key] = append([]string{}, list...)
+		}
+	}
+	return NewRecord(m)
+}
@@ -0,0 +1,183 @@
+// Package gen writes Go model structs for database tables, using the
+// schema introspection of spcdb. The spcdb-gen command wraps it.
+package gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/jenchik/spcdb"
+)
+
+type Options struct {
+	// Package is the package clause of the output, "models" when empty.
+	Package string
+	// Tables to generate; all tables of the current schema when empty.
+	Tables []string
+	// Helpers adds Find<Model> (for single column primary keys) and
+	// Insert<Model> functions built on the spcdb query builder. Insert
+	// leaves a generated or defaulted integer key to the database and
+	// reads it back into the model.
+	Helpers bool
+}
+
+// Generate returns the gofmt'ed source of the models.
+func Generate(ctx context.Context, db *spcdb.DB, opts Options) ([]byte, error) {
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "models"
+	}
+	tables := opts.Tables
+	if len(tables) == 0 {
+		infos, err := db.TablesContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range infos {
+			tables = append(tables, t.Name)
+		}
+	}
+
+	var body bytes.Buffer
+	imports := make(map[string]bool)
+	for _, table := range tables {
+		cols, err := db.ColumnsContext(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("spcdb: Table '%s' not found", table)
+		}
+		writeModel(&body, table, cols, opts.Helpers, imports)
+	}
+	if opts.Helpers && len(tables) > 0 {
+		imports["github.com/jenchik/spcdb"] = true
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by spcdb-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		out.WriteString("import (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		out.WriteString(")\n\n")
+	}
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+func writeModel(buf *bytes.Buffer, table string, cols []spcdb.ColumnInfo, helpers bool, imports map[string]bool) {
+	name := modelName(table)
+	fmt.Fprintf(buf, "// %s is a row of %s.\ntype %s struct {\n", name, table, name)
+	var pk []spcdb.ColumnInfo
+	for _, col := range cols {
+		typ, imp := goType(col)
+		if imp != "" {
+			imports[imp] = true
+		}
+		fmt.Fprintf(buf, "\t%s %s `%s:%q`\n", exportedName(col.Name), typ, spcdb.DefaultTagName, col.Name)
+		if col.PrimaryKey {
+			pk = append(pk, col)
+		}
+	}
+	buf.WriteString("}\n\n")
+	if !helpers {
+		return
+	}
+
+	fmt.Fprintf(buf, "const %sTable = %q\n\n", name, table)
+	if len(pk) == 1 {
+		typ, _ := goType(spcdb.ColumnInfo{Type: pk[0].Type})
+		fmt.Fprintf(buf, "func Find%s(q spcdb.Queryer, %s %s) (*%s, error) {\n", name, "id", typ, name)
+		fmt.Fprintf(buf, "\tvar m %s\n", name)
+		fmt.Fprintf(buf, "\tif err := spcdb.Select().From(%sTable).Where(%q, id).QueryModel(q, &m); err != nil {\n", name, pk[0].Name+" = ?")
+		buf.WriteString("\t\treturn nil, err\n\t}\n\treturn &m, nil\n}\n\n")
+	}
+	fmt.Fprintf(buf, "func Insert%s(q spcdb.Queryer, m *%s) error {\n", name, name)
+	if len(pk) == 1 && (pk[0].Generated || pk[0].Default != nil) && spcdb.ColumnFamily(pk[0].Type) == spcdb.FamilyInt {
+		fmt.Fprintf(buf, "\tid, err := spcdb.Insert(%sTable).Model(m).Omit(%q).ExecReturningID(q, %q)\n", name, pk[0].Name, pk[0].Name)
+		buf.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+		fmt.Fprintf(buf, "\tm.%s = id\n\treturn nil\n}\n\n", exportedName(pk[0].Name))
+		return
+	}
+	fmt.Fprintf(buf, "\t_, err := spcdb.Insert(%sTable).Model(m).Exec(q)\n\treturn err\n}\n\n", name)
+}
+
+// goType maps a database type name to the Go type of its field, a pointer
+// for nullable columns, along with the import it needs.
+func goType(col spcdb.ColumnInfo) (string, string) {
+	var typ, imp string
+	switch spcdb.ColumnFamily(col.Type) {
+	case spcdb.FamilyArray:
+		return "[]string", ""
+	case spcdb.FamilyBool:
+		typ = "bool"
+	case spcdb.FamilyInt:
+		typ = "int64"
+	case spcdb.FamilyNumeric:
+		typ, imp = "big.Rat", "math/big"
+	case spcdb.FamilyFloat:
+		typ = "float64"
+	case spcdb.FamilyJSON:
+		return "json.RawMessage", "encoding/json"
+	case spcdb.FamilyTime:
+		typ, imp = "time.Time", "time"
+	case spcdb.FamilyBytes:
+		return "[]byte", ""
+	default:
+		typ = "string"
+	}
+	if col.Nullable {
+		typ = "*" + typ
+	}
+	return typ, imp
+}
+
+var initialisms = map[string]string{
+	"id": "ID", "uuid": "UUID", "url": "URL", "uri": "URI", "ip": "IP",
+	"api": "API", "http": "HTTP", "json": "JSON", "sql": "SQL", "html": "HTML",
+}
+
+func exportedName(column string) string {
+	var buf strings.Builder
+	for _, part := range strings.FieldsFunc(column, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' ' || r == '.'
+	}) {
+		if s, ok := initialisms[strings.ToLower(part)]; ok {
+			buf.WriteString(s)
+			continue
+		}
+		buf.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	name := buf.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F" + name
+	}
+	return name
+}
+
+// modelName is the exported, naively singularized, table name.
+func modelName(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	switch {
+	case strings.HasSuffix(table, "ies"):
+		table = table[:len(table)-3] + "y"
+	case strings.HasSuffix(table, "ses"), strings.HasSuffix(table, "xes"):
+		table = table[:len(table)-2]
+	case strings.HasSuffix(table, "s") && !strings.HasSuffix(table, "ss"):
+		table = table[:len(table)-1]
+	}
+	return exportedName(table)
+}
@@ -0,0 +1,117 @@
+package spcdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+func init() {
+	// The values Records read from databases, beside gob's basic types.
+	gob.Register(time.Time{})
+	gob.Register(&big.Rat{})
+	gob.Register(map[string]string{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register([][]byte{})
+}
+
+// gobRecord is the wire form of a Record; NULLs are left out of Values
+// and listed in Nulls.
+type gobRecord struct {
+	Keys   []string
+	Values []interface{}
+	Nulls  []string
+	Cols   []ColumnInfo
+}
+
+// MarshalBinary gob-encodes the record, e.g. to cache it. Values of types
+// other than those Records read from databases must be gob.Register'ed.
+func (r *record) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(toGob(r)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the record with one encoded by MarshalBinary.
+func (r *record) UnmarshalBinary(data []byte) error {
+	var w gobRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&w); err != nil {
+		return err
+	}
+	r.fromGob(w)
+	return nil
+}
+
+func (f frozenRecord) MarshalBinary() ([]byte, error) {
+	return f.r.MarshalBinary()
+}
+
+func (f frozenRecord) UnmarshalBinary([]byte) error {
+	panic("spcdb: UnmarshalBinary into a frozen Record")
+}
+
+func toGob(rec Record) gobRecord {
+	w := gobRecord{Cols: rec.Columns()}
+	for _, key := range rec.Keys() {
+		value, _, null := rec.GetOk(key)
+		if null {
+			w.Nulls = append(w.Nulls, key)
+			continue
+		}
+		w.Keys = append(w.Keys, key)
+		w.Values = append(w.Values, value)
+	}
+	return w
+}
+
+func (r *record) fromGob(w gobRecord) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.raw = make(map[string]reflect.Value, len(w.Keys)+len(w.Nulls))
+	r.cols = w.Cols
+	for i, key := range w.Keys {
+		r.raw[key] = reflect.ValueOf(w.Values[i])
+	}
+	for _, key := range w.Nulls {
+		r.raw[key] = reflect.Value{}
+	}
+}
+
+// UnmarshalRecord decodes a Record encoded with MarshalBinary.
+func UnmarshalRecord(data []byte) (Record, error) {
+	rec := &record{}
+	if err := rec.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// EncodeRecords gob-encodes a record set to w, see DecodeRecords.
+func EncodeRecords(w io.Writer, recs []Record) error {
+	list := make([]gobRecord, len(recs))
+	for i, rec := range recs {
+		list[i] = toGob(rec)
+	}
+	return gob.NewEncoder(w).Encode(list)
+}
+
+// DecodeRecords decodes a record set encoded with EncodeRecords.
+func DecodeRecords(r io.Reader) ([]Record, error) {
+	var list []gobRecord
+	if err := gob.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	recs := make([]Record, len(list))
+	for i, w := range list {
+		rec := &record{}
+		rec.fromGob(w)
+		recs[i] = rec
+	}
+	return recs, nil
+}
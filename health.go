@@ -0,0 +1,77 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type HealthReport struct {
+	ConnectionName string
+	Healthy        bool
+	// Latency is the round trip of the validation query.
+	Latency time.Duration
+	Stats   PoolStats
+	// Saturation is the share of the pool in use, from 0 to 1.
+	Saturation float64
+	Err        error
+}
+
+// HealthCheck validates a connection of the pool the way the keepalive
+// does and reports how loaded the pool is. The error is the one in the
+// report, if any. A connection already open is validated in place, busy
+// or not, so a saturated pool is healthy and the check takes no slot.
+func HealthCheck(connectionName string) (HealthReport, error) {
+	return HealthCheckContext(context.Background(), connectionName)
+}
+
+func HealthCheckContext(ctx context.Context, connectionName string) (HealthReport, error) {
+	report := HealthReport{ConnectionName: connectionName}
+	pool, found := getPool(connectionName)
+	if !found {
+		report.Err = fmt.Errorf("spcdb: No DB connection by name '%s'", connectionName)
+		return report, report.Err
+	}
+
+	var err error
+	db := pool.openConn()
+	taken := db == nil
+	if taken {
+		// Nothing open yet, so a slot is free.
+		db, err = GetFromPool(connectionName)
+	}
+	if err == nil {
+		start := time.Now()
+		err = pool.validate(ctx, db)
+		report.Latency = time.Since(start)
+		if taken {
+			db.ReturnToPool()
+		}
+	}
+	// Taken after returning the connection, so the check doesn't count.
+	report.Stats, _ = GetPoolStats(connectionName)
+	if report.Stats.Size > 0 {
+		report.Saturation = float64(report.Stats.Busy) / float64(report.Stats.Size)
+	}
+	report.Err = err
+	report.Healthy = err == nil
+	return report, err
+}
+
+// openConn returns an open connection of the pool, preferring busy ones
+// which eviction leaves alone, or nil.
+func (pool *poolType) openConn() *DB {
+	pool.m.RLock()
+	defer pool.m.RUnlock()
+	var idle *DB
+	for index, db := range pool.conns {
+		if db == nil {
+			continue
+		}
+		if pool.busy[index] {
+			return db
+		}
+		idle = db
+	}
+	return idle
+}
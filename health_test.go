@@ -0,0 +1,35 @@
+package spcdb
+
+import "testing"
+
+type nopConfig struct{}
+
+func (nopConfig) DriverName() string { return "spcdb_nop" }
+func (nopConfig) IsPing() bool       { return false }
+func (nopConfig) String() string     { return "" }
+
+func TestHealthCheckSaturatedPool(t *testing.T) {
+	openNop(t)
+	size := MaxConnsInPool
+	MaxConnsInPool = 1
+	NewPoolConnection("health_test", nopConfig{})
+	MaxConnsInPool = size
+	defer DeletePool("health_test")
+
+	report, err := HealthCheck("health_test")
+	if err != nil || !report.Healthy {
+		t.Fatalf("idle pool: %+v", report)
+	}
+	db, err := GetFromPool("health_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.ReturnToPool()
+	report, err = HealthCheck("health_test")
+	if err != nil || !report.Healthy {
+		t.Fatalf("saturated pool reported unhealthy: %+v", report)
+	}
+	if report.Saturation != 1 {
+		t.Errorf("saturation = %v, want 1", report.Saturation)
+	}
+}
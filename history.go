@@ -0,0 +1,28 @@
+package spcdb
+
+import (
+	"time"
+)
+
+// History tables are expected to hold every version of a row, including
+// the current one, with the period it was valid in: [valid_from, valid_to),
+// valid_to being NULL for the current version.
+var (
+	HistorySuffix    = "_history"
+	HistoryValidFrom = "valid_from"
+	HistoryValidTo   = "valid_to"
+)
+
+// AsOf returns the predicate selecting row versions valid at ts.
+func AsOf(ts time.Time) (string, []interface{}) {
+	cond := HistoryValidFrom + " <= ? AND (" + HistoryValidTo + " IS NULL OR " + HistoryValidTo + " > ?)"
+	return cond, []interface{}{ts, ts}
+}
+
+// AsOf makes the select read the history table of the table given to From
+// and returns the rows as they were at ts, so they decode into the same
+// models as current rows. A later call replaces the time.
+func (b *SelectBuilder) AsOf(ts time.Time) *SelectBuilder {
+	b.asOf = &ts
+	return b
+}
@@ -0,0 +1,108 @@
+package spcdb
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// DecodeHook converts data before it is decoded into a model field of
+// type to; it has the signature of mapstructure.DecodeHookFuncType.
+type DecodeHook func(from, to reflect.Type, data interface{}) (interface{}, error)
+
+var decodeHooks []DecodeHook
+var mHooks sync.RWMutex
+
+// RegisterDecodeHook adds a hook applied by every Model decoding, e.g. to
+// turn strings into uuid.UUID or decimal.Decimal fields.
+func RegisterDecodeHook(hook DecodeHook) {
+	mHooks.Lock()
+	decodeHooks = append(decodeHooks, hook)
+	mHooks.Unlock()
+}
+
+type decodeHooksKey struct{}
+
+// WithDecodeHooks adds hooks for the Model decoding of queries run with
+// the returned context only. They run after the registered ones.
+func WithDecodeHooks(ctx context.Context, hooks ...DecodeHook) context.Context {
+	prev, _ := ctx.Value(decodeHooksKey{}).([]DecodeHook)
+	all := make([]DecodeHook, 0, len(prev)+len(hooks))
+	all = append(append(all, prev...), hooks...)
+	return context.WithValue(ctx, decodeHooksKey{}, all)
+}
+
+// DecodeOptions configures the decoding of a ModelWith call; the zero
+// value decodes like Model.
+type DecodeOptions struct {
+	// TagName overrides the tag name of the DB the record was read from.
+	TagName string
+	// Strict enables StrictDecode for the call.
+	Strict bool
+	// ZeroFields empties maps in dst before decoding instead of merging
+	// into them.
+	ZeroFields bool
+	// Hooks run after the registered ones.
+	Hooks []DecodeHook
+}
+
+func (o DecodeOptions) decodeOptions(conf *config) decodeOptions {
+	opts := conf.decodeOptions()
+	opts.hooks = o.Hooks
+	opts.strict = opts.strict || o.Strict
+	opts.zeroFields = o.ZeroFields
+	if o.TagName != "" {
+		opts.tagName = o.TagName
+	}
+	return opts
+}
+
+type decodeOptions struct {
+	warnings   *Warnings
+	hooks      []DecodeHook
+	strict     bool
+	tagName    string
+	zeroFields bool
+	// conf supplies the time settings.
+	conf *config
+}
+
+func (opts decodeOptions) tag() string {
+	if opts.tagName != "" {
+		return opts.tagName
+	}
+	return DefaultTagName
+}
+
+func decodeOptionsFrom(ctx context.Context, conf *config) decodeOptions {
+	opts := conf.decodeOptions()
+	opts.hooks, _ = ctx.Value(decodeHooksKey{}).([]DecodeHook)
+	opts.warnings = warningsFrom(ctx)
+	opts.strict = opts.strict || strictFrom(ctx)
+	return opts
+}
+
+// decodeHook chains the built-in, registered and per-call hooks. The chain
+// stops once a hook yields nil, as there is nothing left to convert.
+func (opts decodeOptions) decodeHook() DecodeHook {
+	mHooks.RLock()
+	hooks := make([]DecodeHook, 0, len(decodeHooks)+len(opts.hooks)+6)
+	hooks = append(hooks, nullHook, enumHook, timeHook(opts.conf), uuidHook, numericHook)
+	hooks = append(hooks, decodeHooks...)
+	mHooks.RUnlock()
+	hooks = append(hooks, opts.hooks...)
+	if opts.warnings != nil {
+		hooks = append(hooks, lossyHook(opts.warnings))
+	}
+
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		var err error
+		for _, hook := range hooks {
+			if data, err = hook(from, to, data); err != nil || data == nil {
+				return data, err
+			}
+			from = reflect.TypeOf(data)
+		}
+		return data, nil
+	}
+}
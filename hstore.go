@@ -0,0 +1,164 @@
+package spcdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// hstore is an extension type without a fixed OID, so the driver cannot
+// name it; its columns have to be declared to be decoded in Records.
+var hstoreColumns = make(map[string]bool)
+var mHstore sync.RWMutex
+
+// HstoreColumns declares the hstore columns of Postgres databases: they
+// are decoded into map[string]string in Records and models, and such maps
+// are written to them as hstore. Columns the driver reports as hstore
+// need no declaration, and those it reports of another named type are
+// not taken for hstore whatever their name.
+func HstoreColumns(columns ...string) {
+	mHstore.Lock()
+	for _, col := range columns {
+		hstoreColumns[col] = true
+	}
+	mHstore.Unlock()
+}
+
+func isHstoreColumn(column string) bool {
+	mHstore.RLock()
+	defer mHstore.RUnlock()
+	return hstoreColumns[column]
+}
+
+// isHstore tells whether the column of a Postgres result or table holds
+// hstore.
+func isHstore(conf *config, driverName, column, dbType string) bool {
+	if !isPostgres(driverName) {
+		return false
+	}
+	if strings.EqualFold(dbType, "hstore") {
+		return true
+	}
+	return unnamedType(dbType) && conf.isHstoreColumn(column)
+}
+
+// unnamedType tells a type the driver could not name: none at all, the
+// bare OID pgx reports, or information_schema's USER-DEFINED.
+func unnamedType(dbType string) bool {
+	if dbType == "" || dbType == "USER-DEFINED" {
+		return true
+	}
+	for _, c := range dbType {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeHstore(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return parseHstore(string(v))
+	case string:
+		return parseHstore(v)
+	}
+	return nil, fmt.Errorf("spcdb: Cannot decode %T as hstore", value)
+}
+
+// parseHstore reads the text form of hstore, `"k"=>"v", "n"=>NULL`; NULL
+// values become empty strings.
+func parseHstore(src string) (map[string]string, error) {
+	ret := make(map[string]string)
+	i := 0
+	skipSpace := func() {
+		for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n') {
+			i++
+		}
+	}
+	for {
+		skipSpace()
+		if i == len(src) {
+			return ret, nil
+		}
+		key, null, err := hstoreToken(src, &i)
+		if err != nil {
+			return nil, err
+		}
+		if null {
+			return nil, fmt.Errorf("spcdb: NULL hstore key in '%s'", src)
+		}
+		skipSpace()
+		if !strings.HasPrefix(src[i:], "=>") {
+			return nil, fmt.Errorf("spcdb: Unable to parse hstore '%s'", src)
+		}
+		i += 2
+		skipSpace()
+		value, null, err := hstoreToken(src, &i)
+		if err != nil {
+			return nil, err
+		}
+		if null {
+			value = ""
+		}
+		ret[key] = value
+		skipSpace()
+		if i < len(src) {
+			if src[i] != ',' {
+				return nil, fmt.Errorf("spcdb: Unable to parse hstore '%s'", src)
+			}
+			i++
+		}
+	}
+}
+
+// hstoreToken reads a quoted or bare key or value at *i.
+func hstoreToken(src string, i *int) (string, bool, error) {
+	if *i < len(src) && src[*i] == '"' {
+		var buf strings.Builder
+		for *i++; *i < len(src); *i++ {
+			switch src[*i] {
+			case '\\':
+				*i++
+				if *i < len(src) {
+					buf.WriteByte(src[*i])
+				}
+			case '"':
+				*i++
+				return buf.String(), false, nil
+			default:
+				buf.WriteByte(src[*i])
+			}
+		}
+		return "", false, fmt.Errorf("spcdb: Unterminated string in hstore '%s'", src)
+	}
+	start := *i
+	for *i < len(src) && src[*i] != '=' && src[*i] != ',' && src[*i] != ' ' {
+		*i++
+	}
+	token := src[start:*i]
+	if token == "" {
+		return "", false, fmt.Errorf("spcdb: Unable to parse hstore '%s'", src)
+	}
+	return token, strings.EqualFold(token, "NULL"), nil
+}
+
+// formatHstore writes m in the text form of hstore, keys sorted.
+func formatHstore(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeQuoted(&buf, key)
+		buf.WriteString("=>")
+		writeQuoted(&buf, m[key])
+	}
+	return buf.String()
+}
@@ -0,0 +1,30 @@
+package spcdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHstoreDeclaredColumns(t *testing.T) {
+	HstoreColumns("hstore_test_attrs")
+	tests := []struct {
+		dbType string
+		want   interface{}
+	}{
+		{"", map[string]string{"a": "1"}},
+		{"16393", map[string]string{"a": "1"}},
+		{"HSTORE", map[string]string{"a": "1"}},
+		{"TEXT", []byte(`"a"=>"1"`)},
+		{"JSONB", []byte(`"a"=>"1"`)},
+	}
+	for _, tt := range tests {
+		got, err := decodeColumn(defaultConfig, "postgres", "hstore_test_attrs", tt.dbType, []byte(`"a"=>"1"`))
+		if err != nil {
+			t.Errorf("decodeColumn(%q): %v", tt.dbType, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("decodeColumn(%q) = %#v, want %#v", tt.dbType, got, tt.want)
+		}
+	}
+}
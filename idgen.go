@@ -0,0 +1,182 @@
+package spcdb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagOptionsName is the struct tag holding spcdb specific field options,
+// e.g. `spcdb:"genid=uuid7"`.
+var TagOptionsName = "spcdb"
+
+// DefaultIDGenerator is used by fields tagged with a bare "genid".
+var DefaultIDGenerator = "uuid7"
+
+// IDGenerator mints primary keys on the client for fields tagged "genid".
+type IDGenerator interface {
+	NextID(q Queryer, table, column string) (interface{}, error)
+}
+
+var idGenerators = map[string]IDGenerator{
+	"uuid7":     UUIDv7Generator{},
+	"snowflake": NewSnowflakeGenerator(0),
+	"sequence":  SequenceGenerator{},
+}
+var mIDGenerators sync.RWMutex
+
+func RegisterIDGenerator(name string, gen IDGenerator) {
+	mIDGenerators.Lock()
+	idGenerators[name] = gen
+	mIDGenerators.Unlock()
+}
+
+func tagOptions(field reflect.StructField) map[string]string {
+	tag := field.Tag.Get(TagOptionsName)
+	if tag == "" {
+		return nil
+	}
+	opts := make(map[string]string)
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		if i := strings.Index(opt, "="); i >= 0 {
+			opts[opt[:i]] = opt[i+1:]
+		} else {
+			opts[opt] = ""
+		}
+	}
+	return opts
+}
+
+// GenerateIDs fills zero-valued "genid" fields of the model, which must
+// be a pointer to a struct.
+func GenerateIDs(q Queryer, table string, model interface{}) error {
+	val := reflect.ValueOf(model)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("spcdb: GenerateIDs expects a pointer to a struct, got %T", model)
+	}
+	return generateIDs(q, table, val.Elem())
+}
+
+func generateIDs(q Queryer, table string, val reflect.Value) error {
+	for _, info := range structInfoFor(val.Type(), configOf(q).tagName).fields {
+		genName, found := info.options["genid"]
+		if !found {
+			continue
+		}
+		field, ok := fieldByIndex(val, info.index)
+		if !ok || !field.IsZero() || !field.CanSet() {
+			continue
+		}
+		if genName == "" {
+			genName = DefaultIDGenerator
+		}
+		mIDGenerators.RLock()
+		gen, found := idGenerators[genName]
+		mIDGenerators.RUnlock()
+		if !found {
+			return fmt.Errorf("spcdb: No ID generator by name '%s'", genName)
+		}
+
+		id, err := gen.NextID(q, table, info.name)
+		if err != nil {
+			return err
+		}
+		if err = newModel(id, field.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UUIDv7Generator produces time-ordered RFC 9562 version 7 UUIDs as
+// strings.
+type UUIDv7Generator struct{}
+
+func (UUIDv7Generator) NextID(Queryer, string, string) (interface{}, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return nil, err
+	}
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> uint(40-8*i))
+	}
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+// SnowflakeEpoch is the start of the snowflake timestamp (2020-01-01 UTC).
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator produces 63-bit ids: 41 bits of milliseconds since
+// SnowflakeEpoch, 10 bits of node and a 12 bit sequence.
+type SnowflakeGenerator struct {
+	node int64
+	last int64
+	seq  int64
+	m    sync.Mutex
+}
+
+func NewSnowflakeGenerator(node int64) *SnowflakeGenerator {
+	return &SnowflakeGenerator{node: node & 0x3ff}
+}
+
+func (g *SnowflakeGenerator) NextID(Queryer, string, string) (interface{}, error) {
+	g.m.Lock()
+	defer g.m.Unlock()
+	now := int64(time.Since(SnowflakeEpoch) / time.Millisecond)
+	if now < g.last {
+		now = g.last
+	}
+	if now == g.last {
+		g.seq = (g.seq + 1) & 0xfff
+		if g.seq == 0 {
+			for now <= g.last {
+				time.Sleep(time.Millisecond)
+				now = int64(time.Since(SnowflakeEpoch) / time.Millisecond)
+			}
+		}
+	} else {
+		g.seq = 0
+	}
+	g.last = now
+	return now<<22 | g.node<<12 | g.seq, nil
+}
+
+// SequenceGenerator takes ids from a database sequence, by default the
+// serial sequence "<table>_<column>_seq" (PostgreSQL).
+type SequenceGenerator struct {
+	Sequence string
+}
+
+func (g SequenceGenerator) NextID(q Queryer, table, column string) (interface{}, error) {
+	seq := g.Sequence
+	if seq == "" {
+		seq = table + "_" + column + "_seq"
+	}
+	var id int64
+	rows, err := q.Query(rebind(q.DriverName(), "SELECT nextval(?)"), seq)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("spcdb: Sequence '%s' returned no value", seq)
+	}
+	err = rows.Scan(&id)
+	return id, err
+}
@@ -0,0 +1,72 @@
+package spcdb
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CollisionPolicy int
+
+const (
+	KeepLast CollisionPolicy = iota
+	KeepFirst
+	FailOnCollision
+)
+
+var IndexSeparator = "|"
+
+type IndexOptions struct {
+	Separator   string
+	OnCollision CollisionPolicy
+}
+
+// IndexRecordsBy maps records by the values of the key columns joined with
+// IndexSeparator, e.g. "tenant|sku". Later records win on collision.
+func IndexRecordsBy(recs []Record, keys ...string) map[string]Record {
+	ret, _ := IndexRecordsWith(recs, IndexOptions{Separator: IndexSeparator}, keys...)
+	return ret
+}
+
+// IndexRecordsWith is IndexRecordsBy with a choice of separator, which
+// defaults to IndexSeparator, and of what happens on collision.
+func IndexRecordsWith(recs []Record, opts IndexOptions, keys ...string) (map[string]Record, error) {
+	if opts.Separator == "" {
+		opts.Separator = IndexSeparator
+	}
+	ret := make(map[string]Record, len(recs))
+	parts := make([]string, len(keys))
+	for _, rec := range recs {
+		for i, key := range keys {
+			parts[i] = rec.GetInString(key)
+		}
+		index := strings.Join(parts, opts.Separator)
+		if _, found := ret[index]; found {
+			switch opts.OnCollision {
+			case KeepFirst:
+				continue
+			case FailOnCollision:
+				return nil, fmt.Errorf("spcdb: Duplicate index key '%s'", index)
+			}
+		}
+		ret[index] = rec
+	}
+	return ret, nil
+}
+
+// QueryRecordsMap indexes the result set by the value of keyColumn; later
+// rows win when the value repeats.
+func (db *DB) QueryRecordsMap(keyColumn, query string, args ...interface{}) (map[string]Record, error) {
+	recs, err := db.QueryRecords(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return IndexRecordsBy(recs, keyColumn), nil
+}
+
+func (tx *Tx) QueryRecordsMap(keyColumn, query string, args ...interface{}) (map[string]Record, error) {
+	recs, err := tx.QueryRecords(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return IndexRecordsBy(recs, keyColumn), nil
+}
@@ -0,0 +1,50 @@
+package spcdb
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeJSONColumns makes json and jsonb columns arrive decoded, as
+// map[string]interface{} or []interface{}, instead of raw bytes, unless
+// Options.DecodeJSONColumns says otherwise for the DB.
+var DecodeJSONColumns = false
+
+func isJSONType(dbType string) bool {
+	return dbType == "JSON" || dbType == "JSONB"
+}
+
+func decodeJSONColumn(value interface{}) (interface{}, error) {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return value, nil
+	}
+	var ret interface{}
+	err := json.Unmarshal(raw, &ret)
+	return ret, err
+}
+
+// GetJSON decodes the JSON held by the key into dest.
+func (r *record) GetJSON(key string, dest interface{}) error {
+	var raw []byte
+	switch v := r.Get(key).(type) {
+	case nil:
+		return fmt.Errorf("spcdb: No JSON value by key '%s'", key)
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		// Already decoded with DecodeJSONColumns.
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(raw, dest)
+}
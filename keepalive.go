@@ -0,0 +1,110 @@
+package spcdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PingQuery validates idle pooled connections instead of Ping when set,
+// e.g. "SELECT 1".
+var PingQuery string
+
+// KeepaliveTick is how often the keepalive loop looks for pools due for
+// a check.
+var KeepaliveTick = time.Second
+
+var keepaliveStop chan struct{}
+var mKeepalive sync.Mutex
+
+// StartKeepalive (re)starts the loop evicting expired connections of pools
+// with a lifetime or idle limit and pinging the idle ones of pools
+// configured with IsPing and a positive interval. It is started on package
+// init.
+func StartKeepalive() {
+	StopKeepalive()
+	stop := make(chan struct{})
+	mKeepalive.Lock()
+	keepaliveStop = stop
+	mKeepalive.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(KeepaliveTick):
+				now := time.Now()
+				for _, pool := range registeredPools() {
+					if pool.lifetime > 0 || pool.maxIdle > 0 {
+						pool.evictExpired()
+					}
+					if pool.ping && pool.pingDue(now) {
+						pingPool(pool)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func StopKeepalive() {
+	mKeepalive.Lock()
+	defer mKeepalive.Unlock()
+	if keepaliveStop != nil {
+		close(keepaliveStop)
+		keepaliveStop = nil
+	}
+}
+
+// pingDue tells whether the pool's interval passed since its last check,
+// and if so starts a new one. A pool without a positive interval is never
+// pinged.
+func (pool *poolType) pingDue(now time.Time) bool {
+	interval := pool.pingEvery
+	if interval <= 0 {
+		interval = TimeoutPing * time.Minute
+	}
+	if interval <= 0 {
+		return false
+	}
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	if pool.lastPing.IsZero() {
+		pool.lastPing = now
+		return false
+	}
+	if now.Sub(pool.lastPing) < interval {
+		return false
+	}
+	pool.lastPing = now
+	return true
+}
+
+func pingPool(pool *poolType) {
+	idle := make([]*DB, 0, len(pool.conns))
+	pool.m.RLock()
+	for index, busy := range pool.busy {
+		if !busy && pool.conns[index] != nil {
+			idle = append(idle, pool.conns[index])
+		}
+	}
+	pool.m.RUnlock()
+	for _, db := range idle {
+		if err := pool.validate(context.Background(), db); err != nil {
+			db.markBroken()
+		}
+	}
+}
+
+func (pool *poolType) validate(ctx context.Context, db *DB) error {
+	query := pool.pingQuery
+	if query == "" {
+		query = PingQuery
+	}
+	if query == "" {
+		return db.PingContext(ctx)
+	}
+	_, err := db.DB.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,42 @@
+package spcdb
+
+import (
+	"reflect"
+	"strings"
+)
+
+// KeyNormalizer, when set, is the fallback of Record lookups missing the
+// exact key: the key matches a Record key with the same normalized form.
+// LowerKeys and SnakeKeys are provided, e.g. with SnakeKeys
+// rec.Get("UserID") finds the "user_id" column. Options.KeyNormalizer
+// overrides it per DB.
+var KeyNormalizer func(key string) string
+
+// LowerKeys matches keys case-insensitively.
+func LowerKeys(key string) string {
+	return strings.ToLower(key)
+}
+
+// SnakeKeys matches CamelCase keys with snake_case ones, whatever the case.
+func SnakeKeys(key string) string {
+	return snakeCase(strings.ReplaceAll(key, "-", "_"))
+}
+
+// lookup finds the value of key, normalized by KeyNormalizer if it is not
+// there as is. The lock must be held.
+func (r *record) lookup(key string) (reflect.Value, bool) {
+	if el, ok := r.raw[key]; ok {
+		return el, true
+	}
+	normalize := r.config().normalizer()
+	if normalize == nil {
+		return reflect.Value{}, false
+	}
+	want := normalize(key)
+	for _, k := range r.keys() {
+		if normalize(k) == want {
+			return r.raw[k], true
+		}
+	}
+	return reflect.Value{}, false
+}
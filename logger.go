@@ -0,0 +1,162 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// RedactedArg replaces the arguments hidden from the query log.
+var RedactedArg = "[REDACTED]"
+
+// LogArgMaxLen truncates long string and []byte arguments in the log.
+var LogArgMaxLen = 64
+
+// QueryLog is what a Logger gets for every statement.
+type QueryLog struct {
+	ConnectionName string
+	Query          string
+	// Args are printable copies of the arguments, redacted as configured.
+	Args     []interface{}
+	Duration time.Duration
+	Rows     int64
+	Err      error
+}
+
+type Logger interface {
+	LogQuery(ctx context.Context, entry QueryLog)
+}
+
+type LoggerFunc func(ctx context.Context, entry QueryLog)
+
+func (fn LoggerFunc) LogQuery(ctx context.Context, entry QueryLog) {
+	fn(ctx, entry)
+}
+
+type LogOptions struct {
+	// RedactArgs hides every argument.
+	RedactArgs bool
+	// Redact hides the i-th argument of query when it returns true.
+	Redact func(query string, i int) bool
+}
+
+// loggedRedactions are the options of the logger set, also applied to the
+// arguments kept by Error.
+var loggedRedactions LogOptions
+var mRedactions sync.RWMutex
+
+// SetLogger sends every statement run by the helpers to logger once it
+// is done, replacing the logger set before; a nil logger stops logging.
+// Arguments it redacts are redacted in errors as well.
+func SetLogger(logger Logger, opts LogOptions) {
+	mRedactions.Lock()
+	if logger == nil {
+		opts = LogOptions{}
+	}
+	loggedRedactions = opts
+	mRedactions.Unlock()
+
+	mHooksQuery.Lock()
+	defer mHooksQuery.Unlock()
+	// A new slice, as running queries hold on to the old one.
+	hooks := make([]QueryHook, 0, len(queryHooks)+1)
+	for _, hook := range queryHooks {
+		if _, isLog := hook.(*logHook); !isLog {
+			hooks = append(hooks, hook)
+		}
+	}
+	if logger != nil {
+		hooks = append(hooks, &logHook{logger: logger, opts: opts})
+	}
+	queryHooks = hooks
+}
+
+func (opts LogOptions) hides(query string, i int) bool {
+	return opts.RedactArgs || (opts.Redact != nil && opts.Redact(query, i))
+}
+
+// StdLogger writes one line per statement to l.
+func StdLogger(l *log.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, entry QueryLog) {
+		if entry.Err != nil {
+			l.Printf("spcdb: [%s] %s %v (%s): %v", entry.ConnectionName, entry.Query, entry.Args, entry.Duration, entry.Err)
+			return
+		}
+		l.Printf("spcdb: [%s] %s %v (%s, %d rows)", entry.ConnectionName, entry.Query, entry.Args, entry.Duration, entry.Rows)
+	})
+}
+
+type logHook struct {
+	logger Logger
+	opts   LogOptions
+}
+
+func (h *logHook) BeforeQuery(ctx context.Context, ev *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *logHook) AfterQuery(ctx context.Context, ev *QueryEvent) {
+	h.logger.LogQuery(ctx, QueryLog{
+		ConnectionName: ev.ConnectionName,
+		Query:          ev.Query,
+		Args:           redactArgs(warningsFrom(ctx), ev.Query, ev.Args, h.opts),
+		Duration:       ev.Duration,
+		Rows:           ev.Rows,
+		Err:            ev.Err,
+	})
+}
+
+// redactArgs returns printable copies of args, hiding those any of opts
+// redacts, and reports hidden and cut arguments to w.
+func redactArgs(w *Warnings, query string, args []interface{}, opts ...LogOptions) []interface{} {
+	out := make([]interface{}, len(args))
+next:
+	for i, arg := range args {
+		for _, o := range opts {
+			if o.hides(query, i) {
+				out[i] = RedactedArg
+				w.add(WarnRedactedArg, "", "spcdb: Argument %d redacted from the log", i+1)
+				continue next
+			}
+		}
+		var cut bool
+		if out[i], cut = logArg(arg); cut {
+			w.add(WarnTruncatedString, "", "spcdb: Argument %d cut to %d bytes in the log", i+1, LogArgMaxLen)
+		}
+	}
+	return out
+}
+
+// errorArgs redacts args as the logger set does.
+func errorArgs(query string, args []interface{}) []interface{} {
+	mRedactions.RLock()
+	opts := loggedRedactions
+	mRedactions.RUnlock()
+	return redactArgs(nil, query, args, opts)
+}
+
+// logArg turns an argument into the value the driver would see, cut to a
+// printable size; cut tells whether it was.
+func logArg(arg interface{}) (interface{}, bool) {
+	arg = encodeValue(arg)
+	switch v := arg.(type) {
+	case string:
+		return truncateArg(v)
+	case []byte:
+		return truncateArg(fmt.Sprintf("%x", v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano), false
+	case fmt.Stringer:
+		return truncateArg(v.String())
+	}
+	return arg, false
+}
+
+func truncateArg(s string) (string, bool) {
+	if LogArgMaxLen > 0 && len(s) > LogArgMaxLen {
+		return s[:LogArgMaxLen] + "...", true
+	}
+	return s, false
+}
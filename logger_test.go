@@ -0,0 +1,43 @@
+package spcdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestSetLoggerReplaces(t *testing.T) {
+	db := openNop(t)
+	t.Cleanup(func() { SetLogger(nil, LogOptions{}) })
+
+	var m sync.Mutex
+	logged := make(map[string][]QueryLog)
+	loggerNamed := func(name string) Logger {
+		return LoggerFunc(func(_ context.Context, entry QueryLog) {
+			m.Lock()
+			logged[name] = append(logged[name], entry)
+			m.Unlock()
+		})
+	}
+	SetLogger(loggerNamed("first"), LogOptions{RedactArgs: true})
+	SetLogger(loggerNamed("second"), LogOptions{})
+
+	const query = "UPDATE logged SET name = ?"
+	if _, err := db.ExecAffected(query, "visible"); err != nil {
+		t.Fatal(err)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if n := len(logged["first"]); n != 0 {
+		t.Errorf("replaced logger got %d entries", n)
+	}
+	if n := len(logged["second"]); n != 1 {
+		t.Fatalf("logger got %d entries, want 1: %v", n, logged["second"])
+	}
+	if args := logged["second"][0].Args; len(args) != 1 || args[0] != "visible" {
+		t.Errorf("logged args %v, want the replaced logger's redaction gone", args)
+	}
+	if args := errorArgs(query, []interface{}{"visible"}); args[0] != "visible" {
+		t.Errorf("error args %v still redacted by the replaced logger", args)
+	}
+}
@@ -0,0 +1,75 @@
+package spcdb
+
+import (
+	"reflect"
+)
+
+// MergeStrategy decides the value of key when merging an incoming Record;
+// exists tells whether the key was already there. Returning false keeps
+// the current value, or leaves the key out.
+type MergeStrategy func(key string, current, incoming reflect.Value, exists bool) (reflect.Value, bool)
+
+var (
+	// MergeOverwrite is what Merge does.
+	MergeOverwrite MergeStrategy = func(_ string, _, incoming reflect.Value, _ bool) (reflect.Value, bool) {
+		return incoming, true
+	}
+	// MergeSkipExisting only adds keys, e.g. to fill in defaults.
+	MergeSkipExisting MergeStrategy = func(_ string, _, incoming reflect.Value, exists bool) (reflect.Value, bool) {
+		return incoming, !exists
+	}
+	// MergeNonNil ignores incoming NULLs, see IsNull.
+	MergeNonNil MergeStrategy = func(_ string, _, incoming reflect.Value, _ bool) (reflect.Value, bool) {
+		return incoming, !isNullValue(incoming)
+	}
+)
+
+// MergeResolver settles the keys both Records have with fn; the other
+// incoming keys are added. A nil result stores NULL.
+func MergeResolver(fn func(key string, current, incoming interface{}) interface{}) MergeStrategy {
+	return func(key string, current, incoming reflect.Value, exists bool) (reflect.Value, bool) {
+		if !exists {
+			return incoming, true
+		}
+		value := fn(key, valueInterface(current), valueInterface(incoming))
+		if value == nil {
+			return reflect.Value{}, true
+		}
+		return reflect.ValueOf(value), true
+	}
+}
+
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func (r *record) MergeWith(r2 Record, strategy MergeStrategy) {
+	if r == nil || r2 == nil {
+		return
+	}
+	if strategy == nil {
+		strategy = MergeOverwrite
+	}
+	// Read r2 before locking r: r2 may be r itself, or be merging into r.
+	var keys []string
+	var values []reflect.Value
+	r2.Each(func(key string, incoming reflect.Value) {
+		keys = append(keys, key)
+		values = append(values, incoming)
+	})
+	r.m.Lock()
+	defer r.m.Unlock()
+	for i, key := range keys {
+		current, exists := r.raw[key]
+		if value, ok := strategy(key, current, values[i], exists); ok {
+			r.raw[key] = value
+		}
+	}
+}
+
+func (f frozenRecord) MergeWith(Record, MergeStrategy) {
+	panic("spcdb: Merge into a frozen Record")
+}
@@ -0,0 +1,56 @@
+package spcdb
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMergeStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy MergeStrategy
+		want     map[string]interface{}
+	}{
+		{"overwrite", MergeOverwrite, map[string]interface{}{"a": 10, "b": nil, "c": 30}},
+		{"skip existing", MergeSkipExisting, map[string]interface{}{"a": 1, "b": 2, "c": 30}},
+		{"non-nil", MergeNonNil, map[string]interface{}{"a": 10, "b": 2, "c": 30}},
+	}
+	for _, tt := range tests {
+		rec := NewRecord(map[string]interface{}{"a": 1, "b": 2})
+		rec.MergeWith(NewRecord(map[string]interface{}{"a": 10, "b": nil, "c": 30}), tt.strategy)
+		for key, want := range tt.want {
+			if got := rec.Get(key); got != want {
+				t.Errorf("%s: %s = %v, want %v", tt.name, key, got, want)
+			}
+		}
+	}
+
+	rec := NewRecord(map[string]interface{}{"a": 1})
+	rec.Merge(NewRecord(map[string]interface{}{"a": 2}))
+	if got := rec.Get("a"); got != 2 {
+		t.Errorf("Merge: a = %v, want the incoming 2", got)
+	}
+}
+
+func TestMergeSelfAndCrosswise(t *testing.T) {
+	a := NewRecord(map[string]interface{}{"a": 1})
+	b := NewRecord(map[string]interface{}{"b": 2})
+	a.Merge(a)
+	if got := a.Get("a"); got != 1 {
+		t.Errorf("self merge: a = %v, want 1", got)
+	}
+
+	// Merging both ways at once must neither deadlock nor race.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); a.Merge(b) }()
+		go func() { defer wg.Done(); b.Merge(a) }()
+	}
+	wg.Wait()
+	for _, rec := range []Record{a, b} {
+		if rec.Get("a") != 1 || rec.Get("b") != 2 {
+			t.Errorf("crosswise merge left a = %v, b = %v", rec.Get("a"), rec.Get("b"))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package metrics exports spcdb pool and query metrics to Prometheus.
+package metrics
+
+import (
+	"context"
+
+	"github.com/jenchik/spcdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	Namespace = "spcdb"
+	// Buckets of the query latency histogram, in seconds.
+	Buckets = prometheus.DefBuckets
+)
+
+type collector struct {
+	poolSize *prometheus.Desc
+	poolOpen *prometheus.Desc
+	poolBusy *prometheus.Desc
+	queries  *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// Register adds the spcdb collectors to reg and starts observing queries
+// for it; every registry gets collectors of its own. Registering with the
+// same registry again returns its prometheus.AlreadyRegisteredError. Long
+// queries (spcdb.WithLongQuery) are counted but kept out of the latency
+// histogram.
+func Register(reg prometheus.Registerer) error {
+	c := &collector{
+		poolSize: prometheus.NewDesc(Namespace+"_pool_size", "Maximum connections of the pool.", []string{"connection"}, nil),
+		poolOpen: prometheus.NewDesc(Namespace+"_pool_open", "Open connections of the pool.", []string{"connection"}, nil),
+		poolBusy: prometheus.NewDesc(Namespace+"_pool_busy", "Connections taken from the pool.", []string{"connection"}, nil),
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "queries_total",
+			Help:      "Statements run.",
+		}, []string{"connection"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "query_errors_total",
+			Help:      "Statements failed.",
+		}, []string{"connection"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Statement latency.",
+			Buckets:   Buckets,
+		}, []string{"connection"}),
+	}
+	if err := reg.Register(c); err != nil {
+		return err
+	}
+	spcdb.ObserveQueries(c.observe)
+	return nil
+}
+
+func (c *collector) observe(ctx context.Context, ev spcdb.QueryEvent) {
+	c.queries.WithLabelValues(ev.ConnectionName).Inc()
+	if ev.Err != nil {
+		c.errors.WithLabelValues(ev.ConnectionName).Inc()
+	}
+	if !ev.Long {
+		c.latency.WithLabelValues(ev.ConnectionName).Observe(ev.Duration.Seconds())
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.poolSize
+	ch <- c.poolOpen
+	ch <- c.poolBusy
+	c.queries.Describe(ch)
+	c.errors.Describe(ch)
+	c.latency.Describe(ch)
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for _, name := range spcdb.PoolNames() {
+		stats, found := spcdb.GetPoolStats(name)
+		if !found {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.poolSize, prometheus.GaugeValue, float64(stats.Size), name)
+		ch <- prometheus.MustNewConstMetric(c.poolOpen, prometheus.GaugeValue, float64(stats.Open), name)
+		ch <- prometheus.MustNewConstMetric(c.poolBusy, prometheus.GaugeValue, float64(stats.Busy), name)
+	}
+	c.queries.Collect(ch)
+	c.errors.Collect(ch)
+	c.latency.Collect(ch)
+}
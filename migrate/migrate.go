@@ -0,0 +1,251 @@
+// Package migrate applies versioned SQL migrations with spcdb.
+//
+// Migrations are pairs of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, read from a directory (os.DirFS) or an
+// embed.FS. Applied versions are recorded in a tracking table, and on
+// Postgres and MySQL an advisory lock keeps concurrent migrators apart.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenchik/spcdb"
+)
+
+var (
+	// TableName is the tracking table of applied versions.
+	TableName = "schema_migrations"
+	// LockKey identifies the advisory lock held while migrating.
+	LockKey int64 = 0x73706364626d67
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load reads the migrations in dir of fsys, sorted by version.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".sql")
+		up := strings.HasSuffix(base, ".up")
+		if !up && !strings.HasSuffix(base, ".down") {
+			return nil, fmt.Errorf("spcdb: Migration '%s' is neither .up.sql nor .down.sql", name)
+		}
+		base = strings.TrimSuffix(strings.TrimSuffix(base, ".up"), ".down")
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("spcdb: Migration '%s' doesn't start with a version", name)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, found := byVersion[version]
+		if !found {
+			m = &Migration{Version: version}
+			if len(parts) > 1 {
+				m.Name = parts[1]
+			}
+			byVersion[version] = m
+		}
+		if up {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("spcdb: Migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *spcdb.DB
+	migrations []Migration
+}
+
+func New(db *spcdb.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// ApplyAll applies the pending migrations in order, each in its own
+// transaction, and returns the versions applied.
+func (m *Migrator) ApplyAll(ctx context.Context) ([]int64, error) {
+	applied := make([]int64, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, found := done[mig.Version]; found {
+				continue
+			}
+			if err = m.run(ctx, conn, mig.Version, mig.Up, true, mig.Name); err != nil {
+				return fmt.Errorf("spcdb: Migration %d failed: %w", mig.Version, err)
+			}
+			applied = append(applied, mig.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Rollback reverts the last steps applied migrations and returns the
+// versions reverted.
+func (m *Migrator) Rollback(ctx context.Context, steps int) ([]int64, error) {
+	reverted := make([]int64, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, found := done[mig.Version]; !found {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("spcdb: Migration %d has no down script", mig.Version)
+			}
+			if err = m.run(ctx, conn, mig.Version, mig.Down, false, mig.Name); err != nil {
+				return fmt.Errorf("spcdb: Rollback of %d failed: %w", mig.Version, err)
+			}
+			reverted = append(reverted, mig.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		at, found := done[mig.Version]
+		statuses[i] = Status{Migration: mig, Applied: found, AppliedAt: at}
+	}
+	return statuses, nil
+}
+
+// locked runs fn on one connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lock, unlock := m.lockSQL()
+	if lock != "" {
+		if _, err = conn.ExecContext(ctx, lock); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), unlock)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) lockSQL() (string, string) {
+	key := strconv.FormatInt(LockKey, 10)
+	switch m.db.DriverName() {
+	case "postgres", "pgx":
+		return "SELECT pg_advisory_lock(" + key + ")", "SELECT pg_advisory_unlock(" + key + ")"
+	case "mysql":
+		return "SELECT GET_LOCK('spcdb_migrate_" + key + "', -1)", "SELECT RELEASE_LOCK('spcdb_migrate_" + key + "')"
+	}
+	return "", ""
+}
+
+// applied creates the tracking table if needed and returns when each
+// applied version was applied.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	_, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+TableName+
+		" (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM "+TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, version int64, script string, up bool, name string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	d := spcdb.DialectFor(m.db.DriverName())
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+TableName+" (version, name, applied_at) VALUES ("+
+			d.Placeholder(1)+", "+d.Placeholder(2)+", "+d.Placeholder(3)+")", version, name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+TableName+" WHERE version = "+d.Placeholder(1), version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,178 @@
+package spcdb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// tableModel is the model registered for a table; the columns its tags
+// name depend on the tag name of the DB.
+type tableModel struct {
+	typ reflect.Type
+}
+
+// column is the column of the field with the spcdb option under tag.
+func (tm *tableModel) column(tag, option string) string {
+	col := ""
+	for _, info := range structInfoFor(tm.typ, tag).fields {
+		if _, found := info.options[option]; found {
+			col = info.name
+		}
+	}
+	return col
+}
+
+var (
+	tableModels  = make(map[string]*tableModel)
+	modelTables  = make(map[reflect.Type]string)
+	mTableModels sync.RWMutex
+)
+
+// RegisterModel lets the builders of table follow the spcdb tags of the
+// model, a struct or a pointer to one:
+//
+//	softdelete  the column is set to the current time by Delete instead of
+//	            removing the row, and Select skips rows where it is set.
+//	version     Update only changes the row if the column still has the
+//	            value being set, and increments it; otherwise it fails
+//	            with ErrStaleRecord.
+//	autocreatetime, autoupdatetime
+//	            Insert and Update fill the column with the current time,
+//	            see ServerTimestamps; created_at and updated_at fields
+//	            are taken as such without tags.
+//	pk          the column is (part of) the primary key used by Find,
+//	            DeleteByPK and Save; an "id" field is taken as such
+//	            without tags.
+func RegisterModel(table string, model interface{}) error {
+	typ := reflect.TypeOf(model)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("spcdb: RegisterModel expects a struct, got %T", model)
+	}
+	tm := &tableModel{typ: typ}
+	mTableModels.Lock()
+	tableModels[table] = tm
+	modelTables[typ] = table
+	mTableModels.Unlock()
+	return nil
+}
+
+func getTableModel(table string) *tableModel {
+	mTableModels.RLock()
+	defer mTableModels.RUnlock()
+	return tableModels[table]
+}
+
+// TableNamer is implemented by models naming their table.
+type TableNamer interface {
+	TableName() string
+}
+
+// TableOf names the table of the model, a struct or a pointer to one: the
+// result of its TableName method, else the table it was registered with,
+// else the snake_case plural of the struct name (UserAccount becomes
+// user_accounts).
+func TableOf(model interface{}) string {
+	if namer, ok := model.(TableNamer); ok {
+		return namer.TableName()
+	}
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil {
+		return ""
+	}
+	table, _ := modelTable(typ)
+	return table
+}
+
+// modelTable is the table of the struct type, registering it on first use
+// so that its tags apply to the builders.
+func modelTable(typ reflect.Type) (string, error) {
+	if typ.Kind() != reflect.Struct {
+		return "", fmt.Errorf("spcdb: Model %s is not a struct", typ)
+	}
+	if namer, ok := reflect.New(typ).Interface().(TableNamer); ok {
+		table := namer.TableName()
+		if getTableModel(table) == nil {
+			RegisterModel(table, reflect.Zero(typ).Interface())
+		}
+		return table, nil
+	}
+	mTableModels.RLock()
+	table, found := modelTables[typ]
+	mTableModels.RUnlock()
+	if !found {
+		table = pluralize(snakeCase(typ.Name()))
+		RegisterModel(table, reflect.Zero(typ).Interface())
+	}
+	return table, nil
+}
+
+func snakeCase(name string) string {
+	var buf strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Break before an upper case letter following a lower case
+			// one, or ending an initialism: HTTPRequest is http_request.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				buf.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func pluralize(word string) string {
+	switch {
+	case word == "":
+		return word
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	}
+	return word + "s"
+}
+
+// primaryKey lists the fields tagged "pk", or the "id" field.
+func primaryKey(typ reflect.Type, tag string) ([]*fieldInfo, error) {
+	info := structInfoFor(typ, tag)
+	keys := make([]*fieldInfo, 0, 1)
+	for _, field := range info.fields {
+		if _, found := field.options["pk"]; found {
+			keys = append(keys, field)
+		}
+	}
+	if len(keys) == 0 && info.byName["id"] != nil {
+		keys = append(keys, info.byName["id"])
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("spcdb: Model %s has no primary key", typ)
+	}
+	return keys, nil
+}
+
+func versionColumn(table, tag string) string {
+	if tm := getTableModel(table); tm != nil {
+		return tm.column(tag, "version")
+	}
+	return ""
+}
+
+func softDeleteColumn(table, tag string) string {
+	if tm := getTableModel(table); tm != nil {
+		return tm.column(tag, "softdelete")
+	}
+	return ""
+}
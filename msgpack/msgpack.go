@@ -0,0 +1,68 @@
+// Package msgpack encodes spcdb Records as MessagePack maps, a compact
+// form readable from other languages, e.g. for caches shared with them.
+// Records are gob-encodable without this package, see
+// spcdb.EncodeRecords.
+package msgpack
+
+import (
+	"math/big"
+
+	"github.com/jenchik/spcdb"
+	vmsgpack "github.com/vmihailenco/msgpack/v5"
+)
+
+// Marshal encodes the record as a map. NULLs are nil and NUMERIC values
+// their decimal text.
+func Marshal(rec spcdb.Record) ([]byte, error) {
+	return vmsgpack.Marshal(toMap(rec))
+}
+
+func Unmarshal(data []byte) (spcdb.Record, error) {
+	var m map[string]interface{}
+	if err := vmsgpack.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return spcdb.NewRecord(m), nil
+}
+
+// MarshalRecords encodes a record set as an array of maps.
+func MarshalRecords(recs []spcdb.Record) ([]byte, error) {
+	list := make([]map[string]interface{}, len(recs))
+	for i, rec := range recs {
+		list[i] = toMap(rec)
+	}
+	return vmsgpack.Marshal(list)
+}
+
+func UnmarshalRecords(data []byte) ([]spcdb.Record, error) {
+	var list []map[string]interface{}
+	if err := vmsgpack.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	recs := make([]spcdb.Record, len(list))
+	for i, m := range list {
+		recs[i] = spcdb.NewRecord(m)
+	}
+	return recs, nil
+}
+
+func toMap(rec spcdb.Record) map[string]interface{} {
+	m := make(map[string]interface{}, rec.Len())
+	for _, key := range rec.Keys() {
+		value, _, null := rec.GetOk(key)
+		switch {
+		case null:
+			m[key] = nil
+		case isRat(value):
+			m[key] = rec.GetInString(key)
+		default:
+			m[key] = value
+		}
+	}
+	return m
+}
+
+func isRat(value interface{}) bool {
+	_, ok := value.(*big.Rat)
+	return ok
+}
@@ -0,0 +1,70 @@
+package spcdb
+
+import (
+	"strings"
+)
+
+// NestedSeparator splits column names such as "author.name" into a path
+// of nested model fields. Empty disables nesting.
+var NestedSeparator = "."
+
+// nestColumns groups prefixed columns into nested maps for the decoder:
+// {"author.id": 1} becomes {"author": {"id": 1}}. A group whose columns
+// are all NULL, as after a LEFT JOIN without match, becomes NULL itself.
+func nestColumns(src map[string]interface{}) map[string]interface{} {
+	if NestedSeparator == "" {
+		return src
+	}
+	nested := false
+	for key := range src {
+		if strings.Contains(key, NestedSeparator) {
+			nested = true
+			break
+		}
+	}
+	if !nested {
+		return src
+	}
+
+	ret := make(map[string]interface{}, len(src))
+	groups := make(map[string]map[string]interface{})
+	for key, val := range src {
+		i := strings.Index(key, NestedSeparator)
+		if i <= 0 {
+			ret[key] = val
+			continue
+		}
+		prefix := key[:i]
+		group, found := groups[prefix]
+		if !found {
+			group = make(map[string]interface{})
+			groups[prefix] = group
+		}
+		group[key[i+len(NestedSeparator):]] = val
+	}
+	for prefix, group := range groups {
+		if _, taken := ret[prefix]; taken {
+			// A plain column with the same name wins; keep the
+			// prefixed ones as they came.
+			for key, val := range group {
+				ret[prefix+NestedSeparator+key] = val
+			}
+			continue
+		}
+		if allNull(group) {
+			ret[prefix] = nil
+			continue
+		}
+		ret[prefix] = nestColumns(group)
+	}
+	return ret
+}
+
+func allNull(group map[string]interface{}) bool {
+	for _, val := range group {
+		if val != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package spcdb
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+// IsNull reports whether the key holds NULL: a NULL column, a nil pointer
+// or interface, or a Valuer such as sql.NullString that is not valid. A
+// missing key is not NULL.
+func (r *record) IsNull(key string) bool {
+	_, present, null := r.GetOk(key)
+	return present && null
+}
+
+func (r *record) GetOk(key string) (interface{}, bool, bool) {
+	if r == nil {
+		return nil, false, false
+	}
+	r.m.RLock()
+	el, ok := r.lookup(key)
+	r.m.RUnlock()
+	if !ok {
+		return nil, false, false
+	}
+	if isNullValue(el) {
+		return nil, true, true
+	}
+	return el.Interface(), true, false
+}
+
+func isNullValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+	}
+	if !v.CanInterface() {
+		return false
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return false
+}
+
+func (f frozenRecord) IsNull(key string) bool {
+	return f.r.IsNull(key)
+}
+
+func (f frozenRecord) GetOk(key string) (interface{}, bool, bool) {
+	raw := f.GetRaw(key)
+	if raw == nil {
+		return nil, false, false
+	}
+	if isNullValue(*raw) {
+		return nil, true, true
+	}
+	return raw.Interface(), true, false
+}
@@ -0,0 +1,84 @@
+package spcdb
+
+import (
+	"database/sql"
+	"reflect"
+)
+
+// sqlNull stands for a NULL column while decoding models. A plain nil
+// makes mapstructure leave the field untouched, while NULL has to reset
+// pointers to nil and values to zero.
+type sqlNull struct{}
+
+func markNulls(src map[string]interface{}) {
+	for key, val := range src {
+		switch v := val.(type) {
+		case nil:
+			src[key] = sqlNull{}
+		case map[string]interface{}:
+			markNulls(v)
+		}
+	}
+}
+
+// nullHook decodes NULLs and fills fields implementing sql.Scanner, like
+// the sql.Null* types or *T of a custom type, through their Scan methods.
+func nullHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	_, isNull := data.(sqlNull)
+	if _, nested := data.(map[string]interface{}); nested || sameType(from, to) {
+		return data, nil
+	}
+	if to.Kind() == reflect.Interface {
+		if isNull {
+			return nil, nil
+		}
+		return data, nil
+	}
+	if to.Kind() == reflect.Ptr && to.Implements(scannerType) {
+		if isNull {
+			return nil, nil
+		}
+		return scanInto(to.Elem(), data)
+	}
+	if reflect.PtrTo(to).Implements(scannerType) {
+		if isNull {
+			data = nil
+		}
+		ptr, err := scanInto(to, data)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(ptr).Elem().Interface(), nil
+	}
+	if isNull {
+		if to.Kind() == reflect.Ptr {
+			return nil, nil
+		}
+		return reflect.Zero(to).Interface(), nil
+	}
+	return data, nil
+}
+
+// sameType tells whether from is to, or pointers to either of them, so
+// there is nothing to scan.
+func sameType(from, to reflect.Type) bool {
+	if from == nil {
+		return false
+	}
+	for from.Kind() == reflect.Ptr {
+		from = from.Elem()
+	}
+	for to.Kind() == reflect.Ptr {
+		to = to.Elem()
+	}
+	return from == to
+}
+
+// scanInto returns a new *typ the data is scanned into.
+func scanInto(typ reflect.Type, data interface{}) (interface{}, error) {
+	ptr := reflect.New(typ)
+	if err := ptr.Interface().(sql.Scanner).Scan(data); err != nil {
+		return nil, err
+	}
+	return ptr.Interface(), nil
+}
@@ -0,0 +1,20 @@
+package spcdb
+
+import "testing"
+
+func TestNullIntoMapAndSlice(t *testing.T) {
+	var m struct {
+		Attrs map[string]string `mapstructure:"attrs"`
+		Tags  []string          `mapstructure:"tags"`
+	}
+	rec := NewRecord(map[string]interface{}{"attrs": nil, "tags": nil})
+	if err := rec.Model(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Attrs != nil {
+		t.Errorf("Attrs = %v, want nil", m.Attrs)
+	}
+	if m.Tags != nil {
+		t.Errorf("Tags = %q, want nil", m.Tags)
+	}
+}
@@ -0,0 +1,124 @@
+package spcdb
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// DecodeNumeric turns the text of a NUMERIC column into the value held by
+// Records and passed to Model decoding. The default keeps full precision
+// with *big.Rat; replace it to produce e.g. decimal.Decimal.
+var DecodeNumeric = func(s string) (interface{}, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("spcdb: Invalid numeric '%s'", s)
+	}
+	return r, nil
+}
+
+// NumericBindScale is the number of decimals *big.Rat arguments are bound
+// with when they have no exact decimal representation.
+var NumericBindScale = 18
+
+var (
+	ratType    = reflect.TypeOf(big.Rat{})
+	ratPtrType = reflect.TypeOf(&big.Rat{})
+)
+
+func decodeNumericColumn(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return DecodeNumeric(string(v))
+	case string:
+		return DecodeNumeric(v)
+	}
+	return value, nil
+}
+
+// formatRat prints r as a plain decimal without trailing zeros.
+func formatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := r.FloatString(NumericBindScale)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+func encodeNumeric(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case *big.Rat:
+		if v == nil {
+			return nil, true
+		}
+		return formatRat(v), true
+	case big.Rat:
+		return formatRat(new(big.Rat).Set(&v)), true
+	case *big.Float:
+		if v == nil {
+			return nil, true
+		}
+		return v.Text('f', -1), true
+	case *big.Int:
+		if v == nil {
+			return nil, true
+		}
+		return v.String(), true
+	}
+	return value, false
+}
+
+// numericHook decodes numerics into big.Rat, float, integer and string
+// fields.
+func numericHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	var r *big.Rat
+	switch v := data.(type) {
+	case *big.Rat:
+		if v == nil {
+			return data, nil
+		}
+		r = new(big.Rat).Set(v)
+	case []byte:
+		if to.Kind() != reflect.Float32 && to.Kind() != reflect.Float64 && to != ratType && to != ratPtrType {
+			return data, nil
+		}
+		var ok bool
+		if r, ok = new(big.Rat).SetString(string(v)); !ok {
+			return data, nil
+		}
+	case string:
+		if to != ratType && to != ratPtrType {
+			return data, nil
+		}
+		var ok bool
+		if r, ok = new(big.Rat).SetString(v); !ok {
+			return nil, fmt.Errorf("spcdb: Invalid numeric '%s'", v)
+		}
+	default:
+		return data, nil
+	}
+
+	switch {
+	case to == ratPtrType:
+		return r, nil
+	case to == ratType:
+		return *r, nil
+	}
+	switch to.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, _ := r.Float64()
+		return f, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if r.IsInt() && r.Num().IsInt64() {
+			return r.Num().Int64(), nil
+		}
+		f, _ := r.Float64()
+		return f, nil
+	case reflect.String, reflect.Interface:
+		if to.Kind() == reflect.String {
+			return formatRat(r), nil
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,111 @@
+package spcdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// QueryEvent describes a statement run by the spcdb helpers. Duration
+// covers the statement until its rows are closed.
+type QueryEvent struct {
+	ConnectionName string
+	Driver         string
+	Query          string
+	Args           []interface{}
+	Start          time.Time
+	Duration       time.Duration
+	// Rows is the number of rows read or affected, -1 if unknown.
+	Rows int64
+	Err  error
+	// Long is set for statements run with WithLongQuery.
+	Long bool
+}
+
+// QueryHook is called around every statement. The context returned by
+// BeforeQuery is the one the statement runs with and AfterQuery gets.
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, ev *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, ev *QueryEvent)
+}
+
+type QueryObserver func(ctx context.Context, ev QueryEvent)
+
+func (fn QueryObserver) BeforeQuery(ctx context.Context, ev *QueryEvent) context.Context {
+	return ctx
+}
+
+func (fn QueryObserver) AfterQuery(ctx context.Context, ev *QueryEvent) {
+	fn(ctx, *ev)
+}
+
+var queryHooks []QueryHook
+var mHooksQuery sync.RWMutex
+
+func AddQueryHook(hook QueryHook) {
+	mHooksQuery.Lock()
+	queryHooks = append(queryHooks, hook)
+	mHooksQuery.Unlock()
+}
+
+// ObserveQueries registers fn to be called after every statement.
+func ObserveQueries(fn QueryObserver) {
+	AddQueryHook(fn)
+}
+
+type queryRun struct {
+	ctx   context.Context
+	ev    QueryEvent
+	hooks []QueryHook
+	q     sqlQueryer
+}
+
+// startQuery returns nil when there is nobody to tell.
+func startQuery(ctx context.Context, q sqlQueryer, query string, args []interface{}) *queryRun {
+	mHooksQuery.RLock()
+	hooks := queryHooks
+	mHooksQuery.RUnlock()
+	if len(hooks) == 0 && !watchSlowQueries() {
+		return nil
+	}
+	run := &queryRun{
+		q: q,
+		ev: QueryEvent{
+			Query: query,
+			Args:  args,
+			Start: time.Now(),
+			Rows:  -1,
+			Long:  IsLongQuery(ctx),
+		},
+		hooks: hooks,
+	}
+	if h, ok := q.(handle); ok {
+		run.ev.ConnectionName = h.name
+		run.ev.Driver = h.driver
+	}
+	for _, hook := range hooks {
+		ctx = hook.BeforeQuery(ctx, &run.ev)
+	}
+	run.ctx = ctx
+	return run
+}
+
+func (run *queryRun) context(ctx context.Context) context.Context {
+	if run == nil {
+		return ctx
+	}
+	return run.ctx
+}
+
+func (run *queryRun) end(rows int64, err error) {
+	if run == nil {
+		return
+	}
+	run.ev.Duration = time.Since(run.ev.Start)
+	run.ev.Rows = rows
+	run.ev.Err = err
+	for i := len(run.hooks) - 1; i >= 0; i-- {
+		run.hooks[i].AfterQuery(run.ctx, &run.ev)
+	}
+	run.reportSlow()
+}
@@ -0,0 +1,125 @@
+package spcdb
+
+import (
+	"context"
+	"time"
+)
+
+// Options tune the database/sql pool of a DB and how it maps rows. Zero
+// values leave the defaults, the package globals of the same names; a
+// negative MaxIdleConns keeps no idle connections. Enums stay registered
+// process-wide, as they belong to Go types rather than databases.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// TagName is the struct tag naming model columns, DefaultTagName by
+	// default.
+	TagName string
+	// TimeFormat is the format of times in GetInString and the first
+	// layout tried when decoding times, DefaultTimeFormat by default.
+	TimeFormat string
+	// TimeLayouts replace the layouts tried next: RFC 3339 and the
+	// common SQL forms.
+	TimeLayouts []string
+	// TimeLocation is the zone times are converted to when decoded into
+	// models and formatted by GetInString; strings without a zone are
+	// read in it. Nil keeps times as they come and reads zoneless strings
+	// as UTC.
+	TimeLocation *time.Location
+
+	// StrictDecode, DecodeJSONColumns and RecordKeyOrder override the
+	// globals when set.
+	StrictDecode      *bool
+	DecodeJSONColumns *bool
+	RecordKeyOrder    *KeyOrder
+	KeyNormalizer     func(key string) string
+	CursorFetchSize   int
+	// HstoreColumns and ExcludeColumns, by table name with "" for all
+	// tables, add to those declared with the functions of the same names.
+	HstoreColumns  []string
+	ExcludeColumns map[string][]string
+}
+
+// DBOptionsConfiguer is implemented by pool configurations tuning the
+// database/sql pool of every connection they open.
+type DBOptionsConfiguer interface {
+	Options() Options
+}
+
+func OpenWithOptions(driverName, dataSourceName string, opts Options) (*DB, error) {
+	return openWithOptions(driverName, dataSourceName, opts, nil)
+}
+
+func openWithOptions(driverName, dataSourceName string, opts Options, onOpen func(ctx context.Context, s *Session) error) (*DB, error) {
+	db, err := openDB(driverName, dataSourceName, onOpen)
+	if err != nil {
+		return nil, err
+	}
+	opts.apply(db)
+	return db, nil
+}
+
+func (opts Options) apply(db *DB) {
+	if opts.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+	conf := *db.config()
+	if opts.TagName != "" {
+		conf.tagName = opts.TagName
+	}
+	if opts.TimeFormat != "" {
+		conf.timeFormat = opts.TimeFormat
+	}
+	if opts.TimeLayouts != nil {
+		conf.timeLayouts = append([]string(nil), opts.TimeLayouts...)
+	}
+	if opts.TimeLocation != nil {
+		conf.timeLocation = opts.TimeLocation
+	}
+	if opts.StrictDecode != nil {
+		strict := *opts.StrictDecode
+		conf.strictDecode = &strict
+	}
+	if opts.DecodeJSONColumns != nil {
+		decode := *opts.DecodeJSONColumns
+		conf.decodeJSON = &decode
+	}
+	if opts.RecordKeyOrder != nil {
+		order := *opts.RecordKeyOrder
+		conf.keyOrder = &order
+	}
+	if opts.KeyNormalizer != nil {
+		conf.keyNormalizer = opts.KeyNormalizer
+	}
+	if opts.CursorFetchSize > 0 {
+		conf.cursorFetchSize = opts.CursorFetchSize
+	}
+	if len(opts.HstoreColumns) > 0 {
+		conf.hstoreColumns = make(map[string]bool, len(opts.HstoreColumns))
+		for _, col := range opts.HstoreColumns {
+			conf.hstoreColumns[col] = true
+		}
+	}
+	if len(opts.ExcludeColumns) > 0 {
+		conf.excluded = make(map[string]map[string]bool, len(opts.ExcludeColumns))
+		for table, cols := range opts.ExcludeColumns {
+			set := make(map[string]bool, len(cols))
+			for _, col := range cols {
+				set[col] = true
+			}
+			conf.excluded[table] = set
+		}
+	}
+	db.conf = &conf
+}
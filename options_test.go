@@ -0,0 +1,61 @@
+package spcdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionsPerDB(t *testing.T) {
+	openNop(t)
+	strict, decodeJSON, sorted := true, true, KeyOrderSorted
+	tuned, err := OpenWithOptions("spcdb_nop", "", Options{
+		StrictDecode:      &strict,
+		DecodeJSONColumns: &decodeJSON,
+		RecordKeyOrder:    &sorted,
+		KeyNormalizer:     LowerKeys,
+		HstoreColumns:     []string{"attrs"},
+		ExcludeColumns:    map[string][]string{"": {"secret"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tuned.Close()
+	plain := openNop(t)
+
+	if !tuned.config().strict() || plain.config().strict() {
+		t.Error("StrictDecode leaked between DBs")
+	}
+	if !tuned.config().isExcluded("users", "secret") || plain.config().isExcluded("users", "secret") {
+		t.Error("ExcludeColumns leaked between DBs")
+	}
+
+	raw := []byte(`{"a": 1}`)
+	if got, _ := decodeColumn(tuned.config(), "postgres", "doc", "JSONB", raw); !reflect.DeepEqual(got, map[string]interface{}{"a": 1.0}) {
+		t.Errorf("tuned DB decoded JSON as %#v", got)
+	}
+	if got, _ := decodeColumn(plain.config(), "postgres", "doc", "JSONB", raw); !reflect.DeepEqual(got, raw) {
+		t.Errorf("plain DB decoded JSON as %#v", got)
+	}
+	if got, _ := decodeColumn(tuned.config(), "postgres", "attrs", "", []byte(`"a"=>"1"`)); !reflect.DeepEqual(got, map[string]string{"a": "1"}) {
+		t.Errorf("tuned DB decoded hstore as %#v", got)
+	}
+	if got, _ := decodeColumn(plain.config(), "postgres", "attrs", "", []byte(`"a"=>"1"`)); reflect.DeepEqual(got, map[string]string{"a": "1"}) {
+		t.Error("HstoreColumns leaked between DBs")
+	}
+
+	cols := []ColumnInfo{{Name: "b"}, {Name: "A"}}
+	rec := &record{raw: newRecordOf(defaultConfig, map[string]interface{}{"b": 1, "A": 2}).(*record).raw, cols: cols, conf: tuned.config()}
+	if keys := rec.Keys(); !reflect.DeepEqual(keys, []string{"A", "b"}) {
+		t.Errorf("tuned keys = %v, want sorted", keys)
+	}
+	if rec.Get("a") != 2 {
+		t.Errorf("tuned lookup of %q = %v, want 2", "a", rec.Get("a"))
+	}
+	rec.conf = plain.config()
+	if keys := rec.Keys(); !reflect.DeepEqual(keys, []string{"b", "A"}) {
+		t.Errorf("plain keys = %v, want in column order", keys)
+	}
+	if rec.Get("a") != nil {
+		t.Errorf("plain lookup of %q = %v, want nil", "a", rec.Get("a"))
+	}
+}
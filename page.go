@@ -0,0 +1,70 @@
+package spcdb
+
+import (
+	"context"
+	"strings"
+)
+
+var DefaultPerPage = 20
+
+type Page struct {
+	Number  int
+	PerPage int
+	Total   int64
+	Pages   int
+}
+
+func (p Page) HasNext() bool {
+	return p.Number < p.Pages
+}
+
+func (p Page) HasPrev() bool {
+	return p.Number > 1
+}
+
+// QueryRecordsPage returns one page (numbered from 1) of the query result
+// along with the total number of rows the query matches. The query should
+// have a stable ORDER BY for pages to be consistent.
+func (db *DB) QueryRecordsPage(query string, page, perPage int, args ...interface{}) ([]Record, Page, error) {
+	return queryRecordsPage(context.Background(), db.queryer(), query, page, perPage, args...)
+}
+
+func (db *DB) QueryRecordsPageContext(ctx context.Context, query string, page, perPage int, args ...interface{}) ([]Record, Page, error) {
+	return queryRecordsPage(ctx, db.queryer(), query, page, perPage, args...)
+}
+
+func (tx *Tx) QueryRecordsPage(query string, page, perPage int, args ...interface{}) ([]Record, Page, error) {
+	return queryRecordsPage(context.Background(), tx.queryer(), query, page, perPage, args...)
+}
+
+func queryRecordsPage(ctx context.Context, q sqlQueryer, query string, page, perPage int, args ...interface{}) ([]Record, Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	p := Page{Number: page, PerPage: perPage}
+
+	var err error
+	if p.Total, err = countQuery(ctx, q, query, args...); err != nil {
+		return nil, p, err
+	}
+	p.Pages = int((p.Total + int64(perPage) - 1) / int64(perPage))
+
+	if p.Total == 0 || page > p.Pages {
+		return []Record{}, p, nil
+	}
+	driverName := ""
+	if h, ok := q.(handle); ok {
+		driverName = h.driver
+	}
+	paged := DialectFor(driverName).Paging(query, hasOrderBy(query), perPage, (page-1)*perPage)
+	recs, err := queryRecords(ctx, q, paged, args...)
+	return recs, p, err
+}
+
+func (tx *Tx) QueryRecordsPageContext(ctx context.Context, query string, page, perPage int, args ...interface{}) ([]Record, Page, error) {
+	return queryRecordsPage(ctx, tx.queryer(), query, page, perPage, args...)
+}
@@ -0,0 +1,234 @@
+// Package pgxdb runs the spcdb Record and Model helpers on a native pgx
+// pool instead of database/sql, for pgx's binary protocol, batches and
+// type handling. Values are decoded by pgx, so the database/sql specific
+// column conversions of spcdb don't apply.
+package pgxdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jenchik/spcdb"
+)
+
+type DB struct {
+	*pgxpool.Pool
+}
+
+type Tx struct {
+	pgx.Tx
+}
+
+// querier is satisfied by *pgxpool.Pool and pgx.Tx alike.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+func Open(ctx context.Context, dsn string) (*DB, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{Pool: pool}, nil
+}
+
+func (db *DB) Begin(ctx context.Context) (*Tx, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+func (db *DB) QueryRecords(ctx context.Context, query string, args ...interface{}) ([]spcdb.Record, error) {
+	return queryRecords(ctx, db.Pool, query, args...)
+}
+
+func (db *DB) QueryRecord(ctx context.Context, query string, args ...interface{}) (spcdb.Record, error) {
+	return queryRecord(ctx, db.Pool, query, args...)
+}
+
+func (db *DB) QueryModel(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	return queryModel(ctx, db.Pool, query, model, args...)
+}
+
+func (db *DB) QueryModels(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryModels(ctx, db.Pool, query, dest, args...)
+}
+
+// ExecAffected runs the statement and returns the number of affected rows.
+func (db *DB) ExecAffected(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return execAffected(ctx, db.Pool, query, args...)
+}
+
+// ExecBatch sends all statements in one round trip and returns the rows
+// affected by each.
+func (db *DB) ExecBatch(ctx context.Context, batch *pgx.Batch) ([]int64, error) {
+	return execBatch(ctx, db.Pool, batch)
+}
+
+func (tx *Tx) QueryRecords(ctx context.Context, query string, args ...interface{}) ([]spcdb.Record, error) {
+	return queryRecords(ctx, tx.Tx, query, args...)
+}
+
+func (tx *Tx) QueryRecord(ctx context.Context, query string, args ...interface{}) (spcdb.Record, error) {
+	return queryRecord(ctx, tx.Tx, query, args...)
+}
+
+func (tx *Tx) QueryModel(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	return queryModel(ctx, tx.Tx, query, model, args...)
+}
+
+func (tx *Tx) QueryModels(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryModels(ctx, tx.Tx, query, dest, args...)
+}
+
+func (tx *Tx) ExecAffected(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return execAffected(ctx, tx.Tx, query, args...)
+}
+
+func (tx *Tx) ExecBatch(ctx context.Context, batch *pgx.Batch) ([]int64, error) {
+	return execBatch(ctx, tx.Tx, batch)
+}
+
+// eachRecord calls fn for every row until fn returns false.
+func eachRecord(ctx context.Context, q querier, query string, args []interface{}, fn func(spcdb.Record) bool) error {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	fields := rows.FieldDescriptions()
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+		row := make(map[string]interface{}, len(fields))
+		for i, field := range fields {
+			row[field.Name] = values[i]
+		}
+		if !fn(spcdb.NewRecord(row)) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+func queryRecords(ctx context.Context, q querier, query string, args ...interface{}) ([]spcdb.Record, error) {
+	recs := make([]spcdb.Record, 0)
+	err := eachRecord(ctx, q, query, args, func(rec spcdb.Record) bool {
+		recs = append(recs, rec)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+func queryRecord(ctx context.Context, q querier, query string, args ...interface{}) (spcdb.Record, error) {
+	var found spcdb.Record
+	err := eachRecord(ctx, q, query, args, func(rec spcdb.Record) bool {
+		found = rec
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, sql.ErrNoRows
+	}
+	return found, nil
+}
+
+func queryModel(ctx context.Context, q querier, query string, model interface{}, args ...interface{}) error {
+	rec, err := queryRecord(ctx, q, query, args...)
+	if err != nil {
+		return err
+	}
+	return rec.Model(model)
+}
+
+func queryModels(ctx context.Context, q querier, query string, dest interface{}, args ...interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("spcdb: QueryModels expects a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	var decodeErr error
+	err := eachRecord(ctx, q, query, args, func(rec spcdb.Record) bool {
+		elem := reflect.New(elemType)
+		if decodeErr = rec.Model(elem.Interface()); decodeErr != nil {
+			return false
+		}
+		if !isPtr {
+			elem = elem.Elem()
+		}
+		slice.Set(reflect.Append(slice, elem))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+func execAffected(ctx context.Context, q querier, query string, args ...interface{}) (int64, error) {
+	tag, err := q.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func execBatch(ctx context.Context, q querier, batch *pgx.Batch) ([]int64, error) {
+	results := q.SendBatch(ctx, batch)
+	affected := make([]int64, 0, batch.Len())
+	for i := 0; i < batch.Len(); i++ {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return affected, err
+		}
+		affected = append(affected, tag.RowsAffected())
+	}
+	return affected, results.Close()
+}
+
+// CopyTo streams the result of the query to w with COPY ... TO STDOUT and
+// returns the number of rows copied.
+func (db *DB) CopyTo(ctx context.Context, w io.Writer, query string, format spcdb.CopyFormat) (int64, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	tag, err := conn.Conn().PgConn().CopyTo(ctx, w, spcdb.CopyStatement(query, format))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (tx *Tx) CopyTo(ctx context.Context, w io.Writer, query string, format spcdb.CopyFormat) (int64, error) {
+	tag, err := tx.Conn().PgConn().CopyTo(ctx, w, spcdb.CopyStatement(query, format))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,86 @@
+package spcdb
+
+import (
+	"reflect"
+	"sort"
+)
+
+func (r *record) Pick(keys ...string) Record {
+	if r == nil {
+		return nil
+	}
+	set := keySet(keys)
+	return r.project(func(key string) bool { return set[key] })
+}
+
+func (r *record) Omit(keys ...string) Record {
+	if r == nil {
+		return nil
+	}
+	set := keySet(keys)
+	return r.project(func(key string) bool { return !set[key] })
+}
+
+func (r *record) project(keep func(key string) bool) *record {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	ret := &record{raw: make(map[string]reflect.Value), conf: r.conf}
+	for key, value := range r.raw {
+		if keep(key) {
+			ret.raw[key] = value
+		}
+	}
+	for _, col := range r.cols {
+		if keep(col.Name) {
+			ret.cols = append(ret.cols, col)
+		}
+	}
+	return ret
+}
+
+func (f frozenRecord) Pick(keys ...string) Record {
+	return frozenRecord{f.r.Pick(keys...).(*record)}
+}
+
+func (f frozenRecord) Omit(keys ...string) Record {
+	return frozenRecord{f.r.Omit(keys...).(*record)}
+}
+
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+func (r *record) Columns() []ColumnInfo {
+	if r == nil {
+		return nil
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	ret := make([]ColumnInfo, 0, len(r.raw))
+	seen := make(map[string]bool, len(r.raw))
+	for _, col := range r.cols {
+		if _, ok := r.raw[col.Name]; ok && !seen[col.Name] {
+			seen[col.Name] = true
+			ret = append(ret, col)
+		}
+	}
+	rest := make([]string, 0)
+	for key := range r.raw {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		ret = append(ret, ColumnInfo{Name: key})
+	}
+	return ret
+}
+
+func (f frozenRecord) Columns() []ColumnInfo {
+	return f.r.Columns()
+}
@@ -0,0 +1,147 @@
+package spcdb
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Find loads the row of T's table with the primary key, one value per key
+// column in field order. Soft deleted rows are not found.
+func Find[T any](q Queryer, pk ...interface{}) (*T, error) {
+	b, err := pkSelect(q.DriverName(), reflect.TypeOf((*T)(nil)).Elem(), pk, configOf(q).tagName)
+	if err != nil {
+		return nil, err
+	}
+	model := new(T)
+	if err = b.QueryModel(q, model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// DeleteByPK deletes, or soft deletes, the row of T's table with the
+// primary key; sql.ErrNoRows reports there was none.
+func DeleteByPK[T any](q Queryer, pk ...interface{}) error {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	table, keys, err := pkColumns(typ, len(pk), configOf(q).tagName)
+	if err != nil {
+		return err
+	}
+	b := Delete(table)
+	for i, key := range keys {
+		b.Where(quoteIdent(q.DriverName(), key)+" = ?", pk[i])
+	}
+	res, err := b.Exec(q)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// Save updates the row of the model by its primary key, or inserts it when
+// the key is unset or no such row exists, soft deleted rows included. A
+// single integer key left unset is filled with the one the database
+// generated, unless the field is tagged "genid".
+func Save[T any](q Queryer, model *T) error {
+	val := reflect.ValueOf(model).Elem()
+	tag := configOf(q).tagName
+	table, keys, err := pkColumns(val.Type(), -1, tag)
+	if err != nil {
+		return err
+	}
+	fields, _ := primaryKey(val.Type(), tag)
+	pk := make([]interface{}, len(fields))
+	unset := true
+	for i, field := range fields {
+		f, _ := fieldByIndex(val, field.index)
+		pk[i] = f.Interface()
+		unset = unset && f.IsZero()
+	}
+
+	if !unset {
+		// Rows affected can't tell a missing row from an unchanged one, as
+		// MySQL reports changed rows only.
+		found := Select("1").From(table).Unscoped()
+		b := Update(table).Model(model)
+		b.keys = keys
+		for i, key := range keys {
+			found.Where(quoteIdent(q.DriverName(), key)+" = ?", pk[i])
+			b.Where(quoteIdent(q.DriverName(), key)+" = ?", pk[i])
+		}
+		query, args := found.SQL(q.DriverName())
+		exists, err := q.Exists(query, args...)
+		if err != nil {
+			return err
+		}
+		if exists {
+			_, err = b.Exec(q)
+			return err
+		}
+	}
+
+	b := Insert(table).Model(model)
+	_, generated := fields[0].options["genid"]
+	if len(fields) != 1 || !unset || generated || !isIntKind(val.Type().FieldByIndex(fields[0].index).Type.Kind()) {
+		// Insert fills "genid" keys and sends them as given.
+		_, err = b.Exec(q)
+		return err
+	}
+	b.omit = keys
+	id, err := b.ExecReturningID(q, keys[0])
+	if err != nil {
+		return err
+	}
+	f, _ := fieldByIndex(val, fields[0].index)
+	return newModel(id, f.Addr().Interface())
+}
+
+func pkSelect(driverName string, typ reflect.Type, pk []interface{}, tag string) (*SelectBuilder, error) {
+	table, keys, err := pkColumns(typ, len(pk), tag)
+	if err != nil {
+		return nil, err
+	}
+	b := Select().From(table)
+	for i, key := range keys {
+		b.Where(quoteIdent(driverName, key)+" = ?", pk[i])
+	}
+	return b, nil
+}
+
+// pkColumns returns the table and key columns of the model type, checking
+// the number of key values unless it is negative.
+func pkColumns(typ reflect.Type, values int, tag string) (string, []string, error) {
+	table, err := modelTable(typ)
+	if err != nil {
+		return "", nil, err
+	}
+	fields, err := primaryKey(typ, tag)
+	if err != nil {
+		return "", nil, err
+	}
+	if values >= 0 && values != len(fields) {
+		return "", nil, fmt.Errorf("spcdb: Model %s has %d primary key columns, got %d values", typ, len(fields), values)
+	}
+	keys := make([]string, len(fields))
+	for i, field := range fields {
+		keys[i] = field.name
+	}
+	return table, keys, nil
+}
+
+func expectAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err == nil && affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
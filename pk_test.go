@@ -0,0 +1,117 @@
+package spcdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recDriver records the statements it executes and the queries it is
+// sent, which fail.
+type recDriver struct {
+	m       sync.Mutex
+	execs   []recExec
+	queries []string
+}
+
+type recExec struct {
+	query string
+	args  []driver.Value
+}
+
+type recConn struct{ d *recDriver }
+type recStmt struct {
+	d     *recDriver
+	query string
+}
+
+func (d *recDriver) Open(string) (driver.Conn, error) { return recConn{d}, nil }
+
+func (c recConn) Prepare(query string) (driver.Stmt, error) { return recStmt{c.d, query}, nil }
+func (c recConn) Close() error                              { return nil }
+func (c recConn) Begin() (driver.Tx, error)                 { return nil, errors.New("rec: no transactions") }
+
+func (s recStmt) Close() error  { return nil }
+func (s recStmt) NumInput() int { return -1 }
+func (s recStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.m.Lock()
+	s.d.execs = append(s.d.execs, recExec{s.query, args})
+	s.d.m.Unlock()
+	return driver.RowsAffected(1), nil
+}
+func (s recStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.m.Lock()
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.m.Unlock()
+	return nil, errors.New("rec: no rows")
+}
+
+var (
+	recorder         = &recDriver{}
+	registerRecorder sync.Once
+)
+
+func openRecorder(t *testing.T) *DB {
+	return openRecorderAs(t, "spcdb_rec")
+}
+
+func openRecorderAs(t *testing.T, driverName string) *DB {
+	registerRecorder.Do(func() {
+		sql.Register("spcdb_rec", recorder)
+		sql.Register("spcdb_rec_mssql", recorder)
+		RegisterDriverDefaults("spcdb_rec_mssql", SQLServerDialect)
+	})
+	recorder.m.Lock()
+	recorder.execs, recorder.queries = nil, nil
+	recorder.m.Unlock()
+	db, err := Open(driverName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fixedIDGenerator int64
+
+func (g fixedIDGenerator) NextID(Queryer, string, string) (interface{}, error) {
+	return int64(g), nil
+}
+
+type genTicket struct {
+	ID   int64  `mapstructure:"id" spcdb:"pk,genid=fixed_test"`
+	Name string `mapstructure:"name"`
+}
+
+func TestSaveGeneratedIntKey(t *testing.T) {
+	db := openRecorder(t)
+	RegisterIDGenerator("fixed_test", fixedIDGenerator(42))
+	if err := RegisterModel("gen_tickets", genTicket{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ticket := &genTicket{Name: "a"}
+	if err := Save(db, ticket); err != nil {
+		t.Fatal(err)
+	}
+	if ticket.ID != 42 {
+		t.Errorf("ID = %d, want the generated 42", ticket.ID)
+	}
+	if len(recorder.execs) != 1 {
+		t.Fatalf("%d statements, want one INSERT: %v", len(recorder.execs), recorder.execs)
+	}
+	ins := recorder.execs[0]
+	if !strings.HasPrefix(ins.query, "INSERT") || !strings.Contains(ins.query, "id") {
+		t.Fatalf("statement %q does not insert the key", ins.query)
+	}
+	found := false
+	for _, arg := range ins.args {
+		found = found || arg == int64(42)
+	}
+	if !found {
+		t.Errorf("generated key missing from the arguments %v", ins.args)
+	}
+}
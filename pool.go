@@ -1,6 +1,7 @@
 package spcdb
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -10,6 +11,10 @@ var (
 	MaxConnsInPool    int           = 20
 	TimeoutPing       time.Duration = 2 // in minutes
 	DefaultDriverName string        = "postgres"
+	// MaxConnLifetime and MaxIdleTime apply to pools whose configuration
+	// doesn't implement DBLifetimeConfiguer; zero means no limit.
+	MaxConnLifetime time.Duration
+	MaxIdleTime     time.Duration
 )
 
 type DBConfiguer interface {
@@ -18,21 +23,69 @@ type DBConfiguer interface {
 	String()     string
 }
 
+// DSNLister is implemented by configurations listing standby endpoints
+// next to the one String returns; they are tried in order when the
+// current one doesn't answer.
+type DSNLister interface {
+	DSNs() []string
+}
+
+// DBLifetimeConfiguer is implemented by configurations limiting how long
+// the pool keeps a connection. Connections older than the lifetime are
+// reopened on the next acquire; idle ones are closed by the background
+// check.
+type DBLifetimeConfiguer interface {
+	MaxConnLifetime() time.Duration
+	MaxIdleTime() time.Duration
+}
+
+// DBPingConfiguer is implemented by configurations with their own
+// keepalive interval (zero means TimeoutPing) or validation query (empty
+// means PingQuery).
+type DBPingConfiguer interface {
+	PingInterval() time.Duration
+	PingQuery() string
+}
+
 type poolType struct {
-	conns  []*DB
-	busy   []bool
-	driver string
-	dsn    string
-	ping   bool
-	m      sync.RWMutex
+	conns []*DB
+	busy  []bool
+	// freeing marks busy slots being returned, for OnRelease to run
+	// once.
+	freeing []bool
+	// idle holds the returned slots nobody uses, unopened the slots
+	// without a connection yet; both are only pushed to with m held. An
+	// idle slot may have lost its connection to eviction and reconnects
+	// when taken.
+	idle     chan int
+	unopened chan int
+	driver   string
+	dsns     []string
+	active   int
+	ping     bool
+	// opened and used tell when each slot connected and was last returned.
+	opened   []time.Time
+	used     []time.Time
+	lifetime time.Duration
+	maxIdle  time.Duration
+	// pingEvery and pingQuery override the keepalive defaults.
+	pingEvery time.Duration
+	pingQuery string
+	lastPing  time.Time
+	options   Options
+	// retired pools close their connections as they come back.
+	retired bool
+	m       sync.RWMutex
 }
 
 type ptrType struct {
 	index    int
 	nameConn string
+	pool     *poolType
 }
 
 var pools map[string]*poolType
+var mPools sync.RWMutex
 var poolPtr map[*DB]ptrType
 var mPtr sync.RWMutex
 
@@ -40,30 +93,24 @@ func init() {
 	pools = make(map[string]*poolType, 10)
 	poolPtr = make(map[*DB]ptrType, 40)
 
-	go func() {
-		for {
-			<-time.After(TimeoutPing * time.Minute)
-			for _, pool := range pools {
-				if pool.ping {
-					pingPool(pool)
-				}
-			}
-		}
-	}()
+	StartKeepalive()
 }
 
-func pingPool(pool *poolType) {
-	freePools := make([]bool, MaxConnsInPool)
-	pool.m.RLock()
-	copy(freePools, pool.busy)
-	pool.m.RUnlock()
-	for index, busy := range freePools {
-		if !busy {
-			if db := pool.conns[index]; db != nil {
-				db.Ping()
-			}
-		}
+func getPool(connectionName string) (*poolType, bool) {
+	mPools.RLock()
+	defer mPools.RUnlock()
+	pool, found := pools[connectionName]
+	return pool, found
+}
+
+func registeredPools() []*poolType {
+	mPools.RLock()
+	defer mPools.RUnlock()
+	list := make([]*poolType, 0, len(pools))
+	for _, pool := range pools {
+		list = append(list, pool)
 	}
+	return list
 }
 
 func (db *DB) ConnectionName() string {
@@ -84,41 +131,180 @@ func NewPoolConnection(connectionName string, cfg DBConfiguer) {
 	if drvName == "" {
 		drvName = DefaultDriverName
 	}
-	pools[connectionName] = &poolType{
-		conns:  make([]*DB, MaxConnsInPool),
-		busy:   make([]bool, MaxConnsInPool),
-		driver: drvName,
-		dsn:    cfg.String(),
-		ping:   cfg.IsPing(),
+	dsns := []string{cfg.String()}
+	if lister, ok := cfg.(DSNLister); ok {
+		for _, dsn := range lister.DSNs() {
+			if dsn != dsns[0] {
+				dsns = append(dsns, dsn)
+			}
+		}
+	}
+	pool := &poolType{
+		conns:    make([]*DB, MaxConnsInPool),
+		busy:     make([]bool, MaxConnsInPool),
+		freeing:  make([]bool, MaxConnsInPool),
+		idle:     make(chan int, MaxConnsInPool),
+		unopened: make(chan int, MaxConnsInPool),
+		driver:   drvName,
+		dsns:     dsns,
+		ping:     cfg.IsPing(),
+		opened:   make([]time.Time, MaxConnsInPool),
+		used:     make([]time.Time, MaxConnsInPool),
+		lifetime: MaxConnLifetime,
+		maxIdle:  MaxIdleTime,
+	}
+	if lc, ok := cfg.(DBLifetimeConfiguer); ok {
+		pool.lifetime = lc.MaxConnLifetime()
+		pool.maxIdle = lc.MaxIdleTime()
+	}
+	if oc, ok := cfg.(DBOptionsConfiguer); ok {
+		pool.options = oc.Options()
+	}
+	if pc, ok := cfg.(DBPingConfiguer); ok {
+		pool.pingEvery = pc.PingInterval()
+		pool.pingQuery = pc.PingQuery()
+	}
+	for index := 0; index < MaxConnsInPool; index++ {
+		pool.unopened <- index
+	}
+	mPools.Lock()
+	old := pools[connectionName]
+	pools[connectionName] = pool
+	mPools.Unlock()
+	if old != nil {
+		old.retire()
 	}
 }
 
-func GetFromPool(connectionName string) (*DB, error) {
+// ReplacePool registers cfg under connectionName. Connections taken from
+// the previous pool stay usable and are closed when returned.
+func ReplacePool(connectionName string, cfg DBConfiguer) {
+	NewPoolConnection(connectionName, cfg)
+}
+
+// DeletePool forgets the pool, closing its idle connections now and the
+// busy ones when they are returned.
+func DeletePool(connectionName string) bool {
+	mPools.Lock()
 	pool, found := pools[connectionName]
-	if !found {
-		return nil, fmt.Errorf("spcdb: No DB connection by name '%s'", connectionName)
+	delete(pools, connectionName)
+	mPools.Unlock()
+	if found {
+		pool.retire()
 	}
+	return found
+}
 
+func (pool *poolType) retire() {
+	closing := make([]*DB, 0)
 	pool.m.Lock()
-	defer pool.m.Unlock()
-	for index, busy := range pool.busy {
-		if !busy {
+	pool.retired = true
+drain:
+	for {
+		select {
+		case index := <-pool.idle:
 			if db := pool.conns[index]; db != nil {
-				pool.busy[index] = true
-				return db, nil
+				closing = append(closing, db)
+				pool.conns[index] = nil
 			}
-			db, err := Open(pool.driver, pool.dsn)
-			if err == nil {
-				pool.conns[index] = db
-				mPtr.Lock()
-				poolPtr[db] = ptrType{index, connectionName}
-				mPtr.Unlock()
-				pool.busy[index] = true
-			}
-			return db, err
+		default:
+			break drain
 		}
 	}
-	return nil, fmt.Errorf("spcdb: No idle DB connections; '%s'", connectionName)
+	pool.m.Unlock()
+	for _, db := range closing {
+		forgetConn(db)
+	}
+}
+
+// forgetConn closes a connection no slot refers to any more.
+func forgetConn(db *DB) {
+	mPtr.Lock()
+	delete(poolPtr, db)
+	mPtr.Unlock()
+	if hooks := getPoolHooks(db.name); hooks.OnClose != nil {
+		hooks.OnClose(db)
+	}
+	db.Close()
+}
+
+// discard closes the connection of a busy slot and frees the slot.
+func (pool *poolType) discard(index int, db *DB) {
+	pool.m.Lock()
+	pool.conns[index] = nil
+	pool.busy[index] = false
+	pool.unopened <- index
+	pool.m.Unlock()
+	forgetConn(db)
+}
+
+func GetFromPool(connectionName string) (*DB, error) {
+	pool, found := getPool(connectionName)
+	if !found {
+		return nil, fmt.Errorf("spcdb: No DB connection by name '%s'", connectionName)
+	}
+
+	var index int
+	select {
+	case index = <-pool.idle:
+	default:
+		// Open a new connection only when no open one is idle.
+		select {
+		case index = <-pool.idle:
+		case index = <-pool.unopened:
+		default:
+			return nil, fmt.Errorf("spcdb: No idle DB connections; '%s'", connectionName)
+		}
+	}
+
+	pool.m.Lock()
+	pool.busy[index] = true
+	db := pool.conns[index]
+	expired := db != nil && pool.expired(index, time.Now())
+	if expired {
+		pool.conns[index] = nil
+	}
+	pool.m.Unlock()
+	if expired {
+		forgetConn(db)
+		db = nil
+	}
+
+	hooks := getPoolHooks(connectionName)
+	if db == nil {
+		var err error
+		if db, err = pool.connect(connectionName, index, hooks); err != nil {
+			return nil, err
+		}
+	}
+	if hooks.OnAcquire != nil {
+		if err := hooks.OnAcquire(db); err != nil {
+			pool.discard(index, db)
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// connect opens the connection of a busy slot.
+func (pool *poolType) connect(connectionName string, index int, hooks PoolHooks) (*DB, error) {
+	db, err := pool.open(hooks)
+	if err != nil {
+		pool.m.Lock()
+		pool.busy[index] = false
+		pool.unopened <- index
+		pool.m.Unlock()
+		return nil, err
+	}
+	db.name = connectionName
+	pool.m.Lock()
+	pool.conns[index] = db
+	pool.opened[index] = time.Now()
+	pool.m.Unlock()
+	mPtr.Lock()
+	poolPtr[db] = ptrType{index, connectionName, pool}
+	mPtr.Unlock()
+	return db, nil
 }
 
 func ReturnToPool(db *DB) bool {
@@ -128,12 +314,185 @@ func ReturnToPool(db *DB) bool {
 	if !found {
 		return false
 	}
-	pool, found := pools[ptr.nameConn]
-	if !found {
+	pool := ptr.pool
+	pool.m.Lock()
+	if !pool.busy[ptr.index] || pool.freeing[ptr.index] {
+		pool.m.Unlock()
 		return false
 	}
+	pool.freeing[ptr.index] = true
+	pool.m.Unlock()
+	if hooks := getPoolHooks(ptr.nameConn); hooks.OnRelease != nil {
+		hooks.OnRelease(db)
+	}
 	pool.m.Lock()
+	pool.freeing[ptr.index] = false
 	pool.busy[ptr.index] = false
+	if pool.retired || db.isBroken() {
+		if !pool.retired {
+			pool.unopened <- ptr.index
+		}
+		pool.conns[ptr.index] = nil
+		pool.m.Unlock()
+		forgetConn(db)
+		return true
+	}
+	pool.used[ptr.index] = time.Now()
+	pool.idle <- ptr.index
 	pool.m.Unlock()
 	return true
 }
+
+type PoolStats struct {
+	Size int
+	Open int
+	Busy int
+}
+
+func PoolNames() []string {
+	mPools.RLock()
+	defer mPools.RUnlock()
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	return names
+}
+
+func GetPoolStats(connectionName string) (PoolStats, bool) {
+	pool, found := getPool(connectionName)
+	if !found {
+		return PoolStats{}, false
+	}
+	pool.m.RLock()
+	defer pool.m.RUnlock()
+	stats := PoolStats{Size: len(pool.conns)}
+	for index, db := range pool.conns {
+		if db != nil {
+			stats.Open++
+		}
+		if pool.busy[index] {
+			stats.Busy++
+		}
+	}
+	return stats, true
+}
+
+// open connects to the endpoint that answered last, or to the next one
+// that does.
+func (pool *poolType) open(hooks PoolHooks) (*DB, error) {
+	if len(pool.dsns) == 1 {
+		db, err := openWithOptions(pool.driver, pool.dsns[0], pool.options, hooks.OnOpen)
+		if err == nil && hooks.OnOpen != nil {
+			// Connect now for a failing OnOpen to fail the acquire.
+			if err = db.Ping(); err != nil {
+				db.Close()
+				return nil, err
+			}
+		}
+		return db, err
+	}
+	pool.m.RLock()
+	active := pool.active
+	pool.m.RUnlock()
+	var lastErr error
+	for i := range pool.dsns {
+		index := (active + i) % len(pool.dsns)
+		db, err := openWithOptions(pool.driver, pool.dsns[index], pool.options, hooks.OnOpen)
+		if err == nil {
+			if err = db.Ping(); err == nil {
+				pool.m.Lock()
+				pool.active = index
+				pool.m.Unlock()
+				return db, nil
+			}
+			db.Close()
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// ActiveDSN returns the endpoint new connections of the pool go to.
+func ActiveDSN(connectionName string) (string, bool) {
+	pool, found := getPool(connectionName)
+	if !found {
+		return "", false
+	}
+	pool.m.RLock()
+	defer pool.m.RUnlock()
+	return pool.dsns[pool.active], true
+}
+
+// expired reports a connection past its lifetime, idle for too long or
+// broken; m must be held.
+func (pool *poolType) expired(index int, now time.Time) bool {
+	if db := pool.conns[index]; db != nil && db.isBroken() {
+		return true
+	}
+	if pool.maxIdle > 0 && !pool.used[index].IsZero() && now.Sub(pool.used[index]) > pool.maxIdle {
+		return true
+	}
+	return pool.lifetime > 0 && now.Sub(pool.opened[index]) > pool.lifetime
+}
+
+// evictExpired closes the idle connections past their lifetime or idle
+// for too long. Their slots stay in idle and reconnect on the next
+// acquire, so the channels are never drained behind GetFromPool's back.
+func (pool *poolType) evictExpired() {
+	if pool.lifetime <= 0 && pool.maxIdle <= 0 {
+		return
+	}
+	evicted := make([]*DB, 0)
+	now := time.Now()
+	pool.m.Lock()
+	for index, db := range pool.conns {
+		if db != nil && !pool.busy[index] && pool.expired(index, now) {
+			evicted = append(evicted, db)
+			pool.conns[index] = nil
+		}
+	}
+	pool.m.Unlock()
+	for _, db := range evicted {
+		forgetConn(db)
+	}
+}
+
+// WarmPool opens n connections of the pool up front, so the first requests
+// don't pay for connecting.
+func WarmPool(connectionName string, n int) error {
+	taken := make([]*DB, 0, n)
+	defer func() {
+		for _, db := range taken {
+			db.ReturnToPool()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		db, err := GetFromPool(connectionName)
+		if err != nil {
+			return err
+		}
+		taken = append(taken, db)
+		if err = db.Ping(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithConn runs fn with a connection from the pool and returns it to the
+// pool however fn ends.
+func WithConn(connectionName string, fn func(db *DB) error) error {
+	return WithConnContext(context.Background(), connectionName, fn)
+}
+
+// WithConnContext is WithConn taking the connection as GetFromPoolContext
+// does.
+func WithConnContext(ctx context.Context, connectionName string, fn func(db *DB) error) error {
+	db, err := GetFromPoolContext(ctx, connectionName)
+	if err != nil {
+		return err
+	}
+	defer db.ReturnToPool()
+	return fn(db)
+}
@@ -0,0 +1,125 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// PoolHooks are called by the pool at points of a connection's life.
+type PoolHooks struct {
+	// OnOpen runs on every new session of a pooled DB, i.e. on each
+	// physical connection of its database/sql pool, e.g. to set
+	// search_path, statement_timeout or application_name. An error closes
+	// the session and fails the statement, or the acquire that opened the
+	// DB.
+	OnOpen func(ctx context.Context, s *Session) error
+	// OnAcquire runs on every GetFromPool; an error closes the connection
+	// and fails the acquire.
+	OnAcquire func(db *DB) error
+	OnRelease func(db *DB)
+	OnClose   func(db *DB)
+}
+
+var poolHooks = make(map[string]PoolHooks)
+var mPoolHooks sync.RWMutex
+
+// SetPoolHooks sets the hooks of the pool registered, now or later, as
+// connectionName.
+func SetPoolHooks(connectionName string, hooks PoolHooks) {
+	mPoolHooks.Lock()
+	poolHooks[connectionName] = hooks
+	mPoolHooks.Unlock()
+}
+
+func getPoolHooks(connectionName string) PoolHooks {
+	mPoolHooks.RLock()
+	defer mPoolHooks.RUnlock()
+	return poolHooks[connectionName]
+}
+
+// Session is a physical connection being opened, for OnOpen to set it up.
+type Session struct {
+	conn   driver.Conn
+	driver string
+}
+
+func (s *Session) DriverName() string {
+	return s.driver
+}
+
+// Exec runs a statement on the session.
+func (s *Session) Exec(ctx context.Context, query string, args ...interface{}) error {
+	args = bindArgs(s.driver, args)
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return err
+		}
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	if execer, ok := s.conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, named)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	stmt, err := s.conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, named)
+		return err
+	}
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		values[i] = nv.Value
+	}
+	_, err = stmt.Exec(values)
+	return err
+}
+
+// sessionConnector runs onOpen on the connections it makes.
+type sessionConnector struct {
+	driver.Connector
+	driverName string
+	onOpen     func(ctx context.Context, s *Session) error
+}
+
+func (c sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.onOpen(ctx, &Session{conn: conn, driver: c.driverName}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connectorOf returns the connector database/sql would use for db.
+func connectorOf(db *sql.DB, dataSourceName string) (driver.Connector, error) {
+	drv := db.Driver()
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dataSourceName)
+	}
+	return dsnConnector{drv, dataSourceName}, nil
+}
+
+type dsnConnector struct {
+	drv driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.drv
+}
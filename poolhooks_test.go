@@ -0,0 +1,41 @@
+package spcdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOnOpenPerSession(t *testing.T) {
+	openRecorder(t)
+	sessions := 0
+	db, err := openDB("spcdb_rec", "", func(ctx context.Context, s *Session) error {
+		sessions++
+		return s.Exec(ctx, "SET application_name = ?", "spcdb_test")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Without idle connections every statement gets a new session.
+	db.SetMaxIdleConns(-1)
+	for i := 0; i < 2; i++ {
+		if _, err = db.Exec("UPDATE t SET a = 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sessions != 2 {
+		t.Fatalf("OnOpen ran %d times, want 2", sessions)
+	}
+	want := []string{"SET application_name = ?", "UPDATE t SET a = 1", "SET application_name = ?", "UPDATE t SET a = 1"}
+	if len(recorder.execs) != len(want) {
+		t.Fatalf("statements %v, want %q", recorder.execs, want)
+	}
+	for i, exec := range recorder.execs {
+		if exec.query != want[i] {
+			t.Errorf("statement %d = %q, want %q", i, exec.query, want[i])
+		}
+	}
+	if arg := recorder.execs[0].args; len(arg) != 1 || arg[0] != "spcdb_test" {
+		t.Errorf("session setting args = %v", arg)
+	}
+}
@@ -0,0 +1,15 @@
+// Package pq registers the lib/pq driver for spcdb. spcdb itself no longer
+// links a driver; import this package, or the driver of your choice, for
+// its side effects:
+//
+//	import _ "github.com/jenchik/spcdb/pq"
+package pq
+
+import (
+	"github.com/jenchik/spcdb"
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	spcdb.RegisterDriverDefaults("postgres", spcdb.PostgresDialect)
+}
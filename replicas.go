@@ -0,0 +1,80 @@
+package spcdb
+
+import (
+	"context"
+	"sync"
+)
+
+type ReadPreference int
+
+const (
+	// ReadPrimary sends everything to the pool asked for.
+	ReadPrimary ReadPreference = iota
+	// ReadReplica picks one of the replicas of the pool, falling back to
+	// the pool itself when none of them has a connection to give.
+	ReadReplica
+)
+
+type readPreferenceKey struct{}
+
+// WithReadPreference tells GetFromPoolContext where the queries run with
+// ctx may go. Only use ReadReplica for statements that don't write.
+func WithReadPreference(ctx context.Context, pref ReadPreference) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, pref)
+}
+
+func readPreferenceFrom(ctx context.Context) ReadPreference {
+	pref, _ := ctx.Value(readPreferenceKey{}).(ReadPreference)
+	return pref
+}
+
+type replicaSet struct {
+	names []string
+	next  int
+}
+
+var replicaSets = make(map[string]*replicaSet)
+var mReplicas sync.Mutex
+
+// SetReplicas makes the pools registered as replicaNames the replicas of
+// the primary pool connectionName; no names removes them.
+func SetReplicas(connectionName string, replicaNames ...string) {
+	mReplicas.Lock()
+	defer mReplicas.Unlock()
+	if len(replicaNames) == 0 {
+		delete(replicaSets, connectionName)
+		return
+	}
+	replicaSets[connectionName] = &replicaSet{names: append([]string(nil), replicaNames...)}
+}
+
+// GetReadFromPool takes a connection for read-only work from a replica of
+// connectionName, or from connectionName itself.
+func GetReadFromPool(connectionName string) (*DB, error) {
+	return GetFromPoolContext(WithReadPreference(context.Background(), ReadReplica), connectionName)
+}
+
+// replicaOrder lists the replicas of connectionName round-robin.
+func replicaOrder(connectionName string) []string {
+	mReplicas.Lock()
+	defer mReplicas.Unlock()
+	set, found := replicaSets[connectionName]
+	if !found {
+		return nil
+	}
+	order := make([]string, 0, len(set.names))
+	for i := range set.names {
+		order = append(order, set.names[(set.next+i)%len(set.names)])
+	}
+	set.next = (set.next + 1) % len(set.names)
+	return order
+}
+
+func getFromReplica(connectionName string) (*DB, bool) {
+	for _, name := range replicaOrder(connectionName) {
+		if db, err := GetFromPool(name); err == nil {
+			return db, true
+		}
+	}
+	return nil, false
+}
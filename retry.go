@@ -0,0 +1,122 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy tells the helpers how to retry statements failing with a
+// transient error. Statements run inside a transaction are never retried
+// on their own; the whole transaction has to be. Exec statements may have
+// been applied before failing, so they are only retried on
+// driver.ErrBadConn, which database/sql returns before sending anything,
+// unless RetryWrites is set.
+type RetryPolicy struct {
+	MaxAttempts int
+	// Backoff is the first delay, doubled on every further attempt up to
+	// MaxBackoff (when set).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter randomizes every delay by up to that fraction of it.
+	Jitter float64
+	// Retryable decides which errors are worth another attempt; nil means
+	// IsRetryable.
+	Retryable func(err error) bool
+	// RetryWrites retries Exec statements as queries are, for callers
+	// whose writes are idempotent.
+	RetryWrites bool
+}
+
+// DefaultRetryPolicy applies to every statement without a policy in its
+// context; nil means no retries.
+var DefaultRetryPolicy *RetryPolicy
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy overrides DefaultRetryPolicy for the statements run
+// with ctx; a nil policy turns retries off.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+func retryPolicyFrom(ctx context.Context) *RetryPolicy {
+	if policy, found := ctx.Value(retryPolicyKey{}).(*RetryPolicy); found {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// IsRetryable reports deadlocks, serialization failures and lost
+// connections.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || IsDeadlock(err) || IsSerializationFailure(err) {
+		return true
+	}
+	// Class 08: connection exception.
+	if strings.HasPrefix(ErrorCode(err), "08") {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// delay is the wait before the attempt following the given one.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			d = p.MaxBackoff
+			break
+		}
+	}
+	if p.Jitter > 0 && d > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// retry runs fn under the policy of ctx; write tells an Exec statement.
+func retry(ctx context.Context, q sqlQueryer, write bool, fn func() error) error {
+	policy := retryPolicyFrom(ctx)
+	if h, ok := q.(handle); ok && h.inTx {
+		policy = nil
+	}
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return fn()
+	}
+	retryable := policy.retryable
+	if write && !policy.RetryWrites {
+		retryable = func(err error) bool {
+			return errors.Is(err, driver.ErrBadConn)
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.delay(attempt)):
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// flakyDriver fails every query with a connection reset and counts the
+// attempts; Exec succeeds.
+type flakyDriver struct{ queries int64 }
+type flakyConn struct{ d *flakyDriver }
+type flakyStmt struct{ d *flakyDriver }
+
+func (d *flakyDriver) Open(string) (driver.Conn, error) { return flakyConn{d}, nil }
+
+func (c flakyConn) Prepare(string) (driver.Stmt, error) { return flakyStmt{c.d}, nil }
+func (c flakyConn) Close() error                        { return nil }
+func (c flakyConn) Begin() (driver.Tx, error)           { return nil, errors.New("flaky: no transactions") }
+
+func (s flakyStmt) Close() error  { return nil }
+func (s flakyStmt) NumInput() int { return -1 }
+func (s flakyStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s flakyStmt) Query([]driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(&s.d.queries, 1)
+	return nil, syscall.ECONNRESET
+}
+
+var (
+	flaky         = &flakyDriver{}
+	registerFlaky sync.Once
+)
+
+func TestRetryReturningInsertOnce(t *testing.T) {
+	registerFlaky.Do(func() {
+		sql.Register("spcdb_flaky", flaky)
+		RegisterDriverDefaults("spcdb_flaky", PostgresDialect)
+	})
+	db, err := Open("spcdb_flaky", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := WithRetryPolicy(context.Background(), &RetryPolicy{MaxAttempts: 3})
+
+	atomic.StoreInt64(&flaky.queries, 0)
+	if _, err = db.QueryRecordsContext(ctx, "SELECT id FROM flaky"); !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("query error = %v", err)
+	}
+	if n := atomic.LoadInt64(&flaky.queries); n != 3 {
+		t.Fatalf("query attempted %d times, want 3", n)
+	}
+
+	atomic.StoreInt64(&flaky.queries, 0)
+	if _, err = db.ExecReturningIDContext(ctx, "INSERT INTO flaky (name) VALUES (?)", "a"); !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("insert error = %v", err)
+	}
+	if n := atomic.LoadInt64(&flaky.queries); n != 1 {
+		t.Fatalf("insert attempted %d times, want 1", n)
+	}
+}
@@ -0,0 +1,158 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// QueryScalar scans the first column of the first row into dest.
+func (db *DB) QueryScalar(query string, dest interface{}, args ...interface{}) error {
+	return queryScalar(context.Background(), db.queryer(), query, dest, args...)
+}
+
+func (db *DB) QueryScalarContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryScalar(ctx, db.queryer(), query, dest, args...)
+}
+
+func (db *DB) QueryInt64(query string, args ...interface{}) (int64, error) {
+	var v int64
+	err := db.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func (db *DB) QueryString(query string, args ...interface{}) (string, error) {
+	var v string
+	err := db.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func (db *DB) QueryBool(query string, args ...interface{}) (bool, error) {
+	var v bool
+	err := db.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func (db *DB) QueryTime(query string, args ...interface{}) (time.Time, error) {
+	var v time.Time
+	err := db.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+// QueryColumn appends the first column of every row to dest, which must
+// be a pointer to a slice.
+func (db *DB) QueryColumn(query string, dest interface{}, args ...interface{}) error {
+	return queryColumn(context.Background(), db.queryer(), query, dest, args...)
+}
+
+func (db *DB) QueryColumnContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryColumn(ctx, db.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryColumn(query string, dest interface{}, args ...interface{}) error {
+	return queryColumn(context.Background(), tx.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryColumnContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryColumn(ctx, tx.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryScalar(query string, dest interface{}, args ...interface{}) error {
+	return queryScalar(context.Background(), tx.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryScalarContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryScalar(ctx, tx.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryInt64(query string, args ...interface{}) (int64, error) {
+	var v int64
+	err := tx.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func (tx *Tx) QueryString(query string, args ...interface{}) (string, error) {
+	var v string
+	err := tx.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func (tx *Tx) QueryBool(query string, args ...interface{}) (bool, error) {
+	var v bool
+	err := tx.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func (tx *Tx) QueryTime(query string, args ...interface{}) (time.Time, error) {
+	var v time.Time
+	err := tx.QueryScalar(query, &v, args...)
+	return v, err
+}
+
+func queryScalar(ctx context.Context, q sqlQueryer, query string, dest interface{}, args ...interface{}) error {
+	rows, err := runQuery(ctx, q, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanScalar(rows, dest)
+}
+
+// scanScalar reads the first column of the first row and closes rows.
+func scanScalar(rows *resultRows, dest interface{}) error {
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	return scanFirst(rows, len(cols), dest)
+}
+
+func queryColumn(ctx context.Context, q sqlQueryer, query string, dest interface{}, args ...interface{}) error {
+	sliceVal := reflect.ValueOf(dest)
+	if sliceVal.Kind() != reflect.Ptr || sliceVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("spcdb: QueryColumn expects a pointer to a slice, got %T", dest)
+	}
+	sliceVal = sliceVal.Elem()
+	elemType := sliceVal.Type().Elem()
+
+	rows, err := runQuery(ctx, q, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err = scanFirst(rows, len(cols), elem.Interface()); err != nil {
+			return err
+		}
+		sliceVal.Set(reflect.Append(sliceVal, elem.Elem()))
+	}
+	return rows.Err()
+}
+
+// scanFirst scans the first column into dest, dropping the others.
+func scanFirst(rows *resultRows, numCols int, dest interface{}) error {
+	if numCols == 1 {
+		return rows.Scan(dest)
+	}
+	pointers := make([]interface{}, numCols)
+	pointers[0] = dest
+	for i := 1; i < numCols; i++ {
+		pointers[i] = new(interface{})
+	}
+	return rows.Scan(pointers...)
+}
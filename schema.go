@@ -0,0 +1,329 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type TableInfo struct {
+	Schema string
+	Name   string
+}
+
+// ColumnInfo describes a table column, or the result column of a Record;
+// Default, PrimaryKey and Generated are only known for the former.
+type ColumnInfo struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	Default    *string
+	PrimaryKey bool
+	Position   int
+	// Generated is set for serial, identity and auto-increment columns.
+	Generated bool
+}
+
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+type ForeignKeyInfo struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// Tables lists the tables of the current schema on Postgres and MySQL, of
+// the database on SQLite.
+func (db *DB) Tables() ([]TableInfo, error) {
+	return db.TablesContext(context.Background())
+}
+
+func (db *DB) TablesContext(ctx context.Context) ([]TableInfo, error) {
+	var query string
+	switch {
+	case isPostgres(db.driver):
+		query = "SELECT table_schema, table_name FROM information_schema.tables" +
+			" WHERE table_type = 'BASE TABLE' AND table_schema = current_schema() ORDER BY table_name"
+	case db.driver == "mysql":
+		query = "SELECT table_schema, table_name FROM information_schema.tables" +
+			" WHERE table_type = 'BASE TABLE' AND table_schema = DATABASE() ORDER BY table_name"
+	case isSQLite(db.driver):
+		query = "SELECT 'main', name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	default:
+		return nil, errNoIntrospection(db.driver)
+	}
+	rows, err := db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]TableInfo, 0)
+	for rows.Next() {
+		var t TableInfo
+		if err = rows.Scan(&t.Schema, &t.Name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// Columns describes the columns of table, optionally qualified with its
+// schema, in their order.
+func (db *DB) Columns(table string) ([]ColumnInfo, error) {
+	return db.ColumnsContext(context.Background(), table)
+}
+
+func (db *DB) ColumnsContext(ctx context.Context, table string) ([]ColumnInfo, error) {
+	schema, name := splitTableName(table)
+	var rows *sql.Rows
+	var err error
+	switch {
+	case isPostgres(db.driver):
+		rows, err = db.DB.QueryContext(ctx, rebind(db.driver, "SELECT c.column_name, c.data_type, c.is_nullable = 'YES', c.column_default,"+
+			" EXISTS (SELECT 1 FROM information_schema.table_constraints tc"+
+			" JOIN information_schema.key_column_usage k ON k.constraint_name = tc.constraint_name"+
+			" AND k.table_schema = tc.table_schema AND k.table_name = tc.table_name"+
+			" WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema"+
+			" AND tc.table_name = c.table_name AND k.column_name = c.column_name), c.ordinal_position,"+
+			" c.is_identity = 'YES' OR COALESCE(c.column_default LIKE 'nextval(%', false)"+
+			" FROM information_schema.columns c"+
+			" WHERE c.table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND c.table_name = ?"+
+			" ORDER BY c.ordinal_position"), schema, name)
+	case db.driver == "mysql":
+		rows, err = db.DB.QueryContext(ctx, "SELECT column_name, column_type, is_nullable = 'YES', column_default,"+
+			" column_key = 'PRI', ordinal_position, extra LIKE '%auto_increment%' FROM information_schema.columns"+
+			" WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?"+
+			" ORDER BY ordinal_position", schema, name)
+	case isSQLite(db.driver):
+		return db.sqliteColumns(ctx, name)
+	default:
+		return nil, errNoIntrospection(db.driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols := make([]ColumnInfo, 0)
+	for rows.Next() {
+		var c ColumnInfo
+		var def sql.NullString
+		if err = rows.Scan(&c.Name, &c.Type, &c.Nullable, &def, &c.PrimaryKey, &c.Position, &c.Generated); err != nil {
+			return nil, err
+		}
+		if def.Valid {
+			c.Default = &def.String
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+func (db *DB) sqliteColumns(ctx context.Context, table string) ([]ColumnInfo, error) {
+	rows, err := db.DB.QueryContext(ctx, "PRAGMA table_info("+quoteSQLiteName(table)+")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols := make([]ColumnInfo, 0)
+	keys := make([]int, 0, 1)
+	for rows.Next() {
+		var c ColumnInfo
+		var notNull, pk int
+		var def sql.NullString
+		if err = rows.Scan(&c.Position, &c.Name, &c.Type, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+		c.Position++
+		c.Nullable = notNull == 0 && pk == 0
+		c.PrimaryKey = pk > 0
+		if def.Valid {
+			c.Default = &def.String
+		}
+		if c.PrimaryKey {
+			keys = append(keys, len(cols))
+		}
+		cols = append(cols, c)
+	}
+	// A lone INTEGER PRIMARY KEY is the rowid.
+	if len(keys) == 1 && strings.EqualFold(cols[keys[0]].Type, "INTEGER") {
+		cols[keys[0]].Generated = true
+	}
+	return cols, rows.Err()
+}
+
+// Indexes describes the indexes of table, optionally qualified with its
+// schema, sorted by name.
+func (db *DB) Indexes(table string) ([]IndexInfo, error) {
+	return db.IndexesContext(context.Background(), table)
+}
+
+func (db *DB) IndexesContext(ctx context.Context, table string) ([]IndexInfo, error) {
+	schema, name := splitTableName(table)
+	var rows *sql.Rows
+	var err error
+	switch {
+	case isPostgres(db.driver):
+		rows, err = db.DB.QueryContext(ctx, rebind(db.driver, "SELECT i.relname, ix.indisunique, ix.indisprimary, a.attname"+
+			" FROM pg_index ix"+
+			" JOIN pg_class t ON t.oid = ix.indrelid"+
+			" JOIN pg_class i ON i.oid = ix.indexrelid"+
+			" JOIN pg_namespace n ON n.oid = t.relnamespace"+
+			" JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true"+
+			" JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum"+
+			" WHERE n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?"+
+			" ORDER BY i.relname, k.ord"), schema, name)
+	case db.driver == "mysql":
+		rows, err = db.DB.QueryContext(ctx, "SELECT index_name, non_unique = 0, index_name = 'PRIMARY', column_name"+
+			" FROM information_schema.statistics"+
+			" WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?"+
+			" ORDER BY index_name, seq_in_index", schema, name)
+	case isSQLite(db.driver):
+		return db.sqliteIndexes(ctx, name)
+	default:
+		return nil, errNoIntrospection(db.driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	indexes := make([]IndexInfo, 0)
+	for rows.Next() {
+		var idx IndexInfo
+		var column string
+		if err = rows.Scan(&idx.Name, &idx.Unique, &idx.Primary, &column); err != nil {
+			return nil, err
+		}
+		if n := len(indexes); n > 0 && indexes[n-1].Name == idx.Name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		idx.Columns = []string{column}
+		indexes = append(indexes, idx)
+	}
+	return indexes, rows.Err()
+}
+
+func (db *DB) sqliteIndexes(ctx context.Context, table string) ([]IndexInfo, error) {
+	list, err := db.QueryRecordsContext(ctx, "PRAGMA index_list("+quoteSQLiteName(table)+")")
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]IndexInfo, 0, len(list))
+	for _, rec := range list {
+		idx := IndexInfo{
+			Name:    rec.GetInString("name"),
+			Unique:  rec.GetInString("unique") == "1",
+			Primary: rec.GetInString("origin") == "pk",
+		}
+		info, err := db.QueryRecordsContext(ctx, "PRAGMA index_info("+quoteSQLiteName(idx.Name)+")")
+		if err != nil {
+			return nil, err
+		}
+		for _, col := range info {
+			idx.Columns = append(idx.Columns, col.GetInString("name"))
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, nil
+}
+
+// ForeignKeys describes the foreign keys of table, optionally qualified
+// with its schema.
+func (db *DB) ForeignKeys(table string) ([]ForeignKeyInfo, error) {
+	return db.ForeignKeysContext(context.Background(), table)
+}
+
+func (db *DB) ForeignKeysContext(ctx context.Context, table string) ([]ForeignKeyInfo, error) {
+	schema, name := splitTableName(table)
+	var rows *sql.Rows
+	var err error
+	switch {
+	case isPostgres(db.driver):
+		rows, err = db.DB.QueryContext(ctx, rebind(db.driver, "SELECT con.conname, a.attname, rt.relname, ra.attname"+
+			" FROM pg_constraint con"+
+			" JOIN pg_class t ON t.oid = con.conrelid"+
+			" JOIN pg_namespace n ON n.oid = t.relnamespace"+
+			" JOIN pg_class rt ON rt.oid = con.confrelid"+
+			" JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refnum, ord) ON true"+
+			" JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum"+
+			" JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refnum"+
+			" WHERE con.contype = 'f' AND n.nspname = COALESCE(NULLIF(?, ''), current_schema()) AND t.relname = ?"+
+			" ORDER BY con.conname, k.ord"), schema, name)
+	case db.driver == "mysql":
+		rows, err = db.DB.QueryContext(ctx, "SELECT constraint_name, column_name, referenced_table_name, referenced_column_name"+
+			" FROM information_schema.key_column_usage"+
+			" WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?"+
+			" AND referenced_table_name IS NOT NULL"+
+			" ORDER BY constraint_name, ordinal_position", schema, name)
+	case isSQLite(db.driver):
+		return db.sqliteForeignKeys(ctx, name)
+	default:
+		return nil, errNoIntrospection(db.driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]ForeignKeyInfo, 0)
+	for rows.Next() {
+		var fk ForeignKeyInfo
+		var column, refColumn string
+		if err = rows.Scan(&fk.Name, &column, &fk.RefTable, &refColumn); err != nil {
+			return nil, err
+		}
+		if n := len(keys); n > 0 && keys[n-1].Name == fk.Name {
+			keys[n-1].Columns = append(keys[n-1].Columns, column)
+			keys[n-1].RefColumns = append(keys[n-1].RefColumns, refColumn)
+			continue
+		}
+		fk.Columns, fk.RefColumns = []string{column}, []string{refColumn}
+		keys = append(keys, fk)
+	}
+	return keys, rows.Err()
+}
+
+func (db *DB) sqliteForeignKeys(ctx context.Context, table string) ([]ForeignKeyInfo, error) {
+	list, err := db.QueryRecordsContext(ctx, "PRAGMA foreign_key_list("+quoteSQLiteName(table)+")")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]ForeignKeyInfo, 0)
+	for _, rec := range list {
+		name := rec.GetInString("id")
+		if n := len(keys); n > 0 && keys[n-1].Name == name {
+			keys[n-1].Columns = append(keys[n-1].Columns, rec.GetInString("from"))
+			keys[n-1].RefColumns = append(keys[n-1].RefColumns, rec.GetInString("to"))
+			continue
+		}
+		keys = append(keys, ForeignKeyInfo{
+			Name:       name,
+			Columns:    []string{rec.GetInString("from")},
+			RefTable:   rec.GetInString("table"),
+			RefColumns: []string{rec.GetInString("to")},
+		})
+	}
+	return keys, nil
+}
+
+func splitTableName(table string) (string, string) {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[:i], table[i+1:]
+	}
+	return "", table
+}
+
+func quoteSQLiteName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+func errNoIntrospection(driverName string) error {
+	return fmt.Errorf("spcdb: No schema introspection for driver '%s'", driverName)
+}
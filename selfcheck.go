@@ -0,0 +1,158 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type SelfCheckOptions struct {
+	// Extensions that must be installed (PostgreSQL).
+	Extensions []string
+	// MigrationsTable is checked for presence and dirty entries; empty
+	// skips the check.
+	MigrationsTable string
+	// Models maps table names to model values whose fields must all have
+	// a matching column.
+	Models map[string]interface{}
+}
+
+var DefaultSelfCheck = SelfCheckOptions{
+	Extensions:      []string{"uuid-ossp", "pgcrypto"},
+	MigrationsTable: "schema_migrations",
+}
+
+type CheckResult struct {
+	Name    string
+	OK      bool
+	Message string
+}
+
+type SelfCheckReport struct {
+	ServerVersion string
+	Checks        []CheckResult
+}
+
+func (r *SelfCheckReport) OK() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns an error listing every failed check, or nil.
+func (r *SelfCheckReport) Err() error {
+	failed := make([]string, 0)
+	for _, c := range r.Checks {
+		if !c.OK {
+			failed = append(failed, c.Name+": "+c.Message)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("spcdb: Self-check failed; %s", strings.Join(failed, "; "))
+}
+
+func (r *SelfCheckReport) add(name string, err error, okMessage string) bool {
+	if err != nil {
+		r.Checks = append(r.Checks, CheckResult{name, false, err.Error()})
+		return false
+	}
+	r.Checks = append(r.Checks, CheckResult{name, true, okMessage})
+	return true
+}
+
+// SelfCheck verifies that the database is usable by the service and
+// reports every problem found. A nil opts means DefaultSelfCheck.
+func (db *DB) SelfCheck(ctx context.Context, opts *SelfCheckOptions) *SelfCheckReport {
+	if opts == nil {
+		opts = &DefaultSelfCheck
+	}
+	report := &SelfCheckReport{}
+
+	if !report.add("connectivity", db.PingContext(ctx), "ok") {
+		return report
+	}
+
+	err := db.QueryScalarContext(ctx, "SELECT version()", &report.ServerVersion)
+	report.add("server version", err, report.ServerVersion)
+
+	if len(opts.Extensions) > 0 {
+		report.add("extensions", db.checkExtensions(ctx, opts.Extensions), strings.Join(opts.Extensions, ", "))
+	}
+
+	if opts.MigrationsTable != "" {
+		msg, err := db.checkMigrations(ctx, opts.MigrationsTable)
+		report.add("migrations", err, msg)
+	}
+
+	for table, model := range opts.Models {
+		report.add("model "+table, db.checkModelColumns(ctx, table, model), "ok")
+	}
+	return report
+}
+
+func (db *DB) checkExtensions(ctx context.Context, required []string) error {
+	recs, err := db.QueryRecordsContext(ctx, "SELECT extname FROM pg_extension")
+	if err != nil {
+		return err
+	}
+	installed := IndexRecordsBy(recs, "extname")
+	missing := make([]string, 0)
+	for _, ext := range required {
+		if _, found := installed[ext]; !found {
+			missing = append(missing, ext)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing extensions %s; run CREATE EXTENSION", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (db *DB) checkMigrations(ctx context.Context, table string) (string, error) {
+	recs, err := db.QueryRecordsContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s (%s); have migrations been applied?", table, err)
+	}
+	if len(recs) == 0 {
+		return "", fmt.Errorf("%s is empty; no migrations applied", table)
+	}
+	for _, rec := range recs {
+		if dirty, _ := rec.Get("dirty").(bool); dirty {
+			return "", fmt.Errorf("migration %s is dirty; fix it and clear the flag", rec.GetInString("version"))
+		}
+	}
+	return fmt.Sprintf("%d applied", len(recs)), nil
+}
+
+func (db *DB) checkModelColumns(ctx context.Context, table string, model interface{}) error {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+table+" WHERE 1 = 0")
+	if err != nil {
+		return err
+	}
+	cols, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(cols))
+	for _, col := range cols {
+		present[col] = true
+	}
+
+	missing := make([]string, 0)
+	newRecordOf(db.config(), model).Each(func(key string, _ reflect.Value) {
+		if !present[key] {
+			missing = append(missing, key)
+		}
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("columns %s not found in table", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SerializableTxAttempts limits how many times WithSerializableTx runs the
+// transaction before giving up on serialization failures.
+var SerializableTxAttempts = 10
+
+// SerializableTxBackoff is the delay before the first retry, doubled on
+// every further one.
+var SerializableTxBackoff = 10 * time.Millisecond
+
+// WithSerializableTx runs fn in a SERIALIZABLE transaction, committing it
+// when fn returns nil. Transactions failing with a serialization error
+// (SQLSTATE 40001) are run again from the start, so fn must not have side
+// effects outside of tx.
+func (db *DB) WithSerializableTx(fn func(tx *Tx) error) error {
+	return db.WithSerializableTxContext(context.Background(), fn)
+}
+
+func (db *DB) WithSerializableTxContext(ctx context.Context, fn func(tx *Tx) error) error {
+	policy := RetryPolicy{
+		MaxAttempts: SerializableTxAttempts,
+		Backoff:     SerializableTxBackoff,
+		Jitter:      0.5,
+		Retryable:   IsSerializationFailure,
+	}
+	return retry(WithRetryPolicy(ctx, &policy), nil, false, func() error {
+		return db.runSerializableTx(ctx, fn)
+	})
+}
+
+func (db *DB) runSerializableTx(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
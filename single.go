@@ -0,0 +1,142 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrTooManyRows is returned by QueryRecordStrict and QueryModelStrict
+// when the query matches more than one row, e.g. for a missing WHERE.
+var ErrTooManyRows = errors.New("spcdb: Query matched more than one row")
+
+// QueryRecordStrict is QueryRecord failing with ErrTooManyRows instead of
+// using the first of several rows.
+func (db *DB) QueryRecordStrict(query string, args ...interface{}) (Record, error) {
+	return queryRecordStrict(context.Background(), db.queryer(), query, args...)
+}
+
+func (db *DB) QueryRecordStrictContext(ctx context.Context, query string, args ...interface{}) (Record, error) {
+	return queryRecordStrict(ctx, db.queryer(), query, args...)
+}
+
+// QueryModelStrict is QueryModel failing with ErrTooManyRows instead of
+// using the first of several rows.
+func (db *DB) QueryModelStrict(query string, model interface{}, args ...interface{}) error {
+	return queryModelStrict(context.Background(), db.queryer(), query, model, args...)
+}
+
+func (db *DB) QueryModelStrictContext(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	return queryModelStrict(ctx, db.queryer(), query, model, args...)
+}
+
+func (tx *Tx) QueryRecordStrict(query string, args ...interface{}) (Record, error) {
+	return queryRecordStrict(context.Background(), tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryRecordStrictContext(ctx context.Context, query string, args ...interface{}) (Record, error) {
+	return queryRecordStrict(ctx, tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryModelStrict(query string, model interface{}, args ...interface{}) error {
+	return queryModelStrict(context.Background(), tx.queryer(), query, model, args...)
+}
+
+func (tx *Tx) QueryModelStrictContext(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	return queryModelStrict(ctx, tx.queryer(), query, model, args...)
+}
+
+// querySingle runs the query and calls fn for its only row.
+func querySingle(ctx context.Context, q sqlQueryer, query string, args []interface{}, fn func(rows *resultRows, cols *columnSet) error) error {
+	rows, err := runQuery(ctx, q, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	cols, err := readColumns(ctx, rows)
+	if err != nil {
+		return err
+	}
+	if err = fn(rows, cols); err != nil {
+		return err
+	}
+	if rows.Next() {
+		return ErrTooManyRows
+	}
+	return rows.Err()
+}
+
+func queryRecordStrict(ctx context.Context, q sqlQueryer, query string, args ...interface{}) (Record, error) {
+	var rec Record
+	err := querySingle(ctx, q, query, args, func(rows *resultRows, cols *columnSet) error {
+		var err error
+		rec, err = newRecord(rows, cols, configOf(q))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func queryModelStrict(ctx context.Context, q sqlQueryer, query string, model interface{}, args ...interface{}) error {
+	var container map[string]interface{}
+	err := querySingle(ctx, q, query, args, func(rows *resultRows, cols *columnSet) error {
+		var err error
+		container, err = newModelMap(rows, cols)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return newModelWith(container, model, decodeOptionsFrom(ctx, configOf(q)))
+}
+
+// QueryRecordOrNil is QueryRecord returning a nil Record and no error when
+// no row matches.
+func (db *DB) QueryRecordOrNil(query string, args ...interface{}) (Record, error) {
+	return orNil(queryRecord(context.Background(), db.queryer(), query, args...))
+}
+
+func (db *DB) QueryRecordOrNilContext(ctx context.Context, query string, args ...interface{}) (Record, error) {
+	return orNil(queryRecord(ctx, db.queryer(), query, args...))
+}
+
+func (tx *Tx) QueryRecordOrNil(query string, args ...interface{}) (Record, error) {
+	return orNil(queryRecord(context.Background(), tx.queryer(), query, args...))
+}
+
+func (tx *Tx) QueryRecordOrNilContext(ctx context.Context, query string, args ...interface{}) (Record, error) {
+	return orNil(queryRecord(ctx, tx.queryer(), query, args...))
+}
+
+// QueryModelOrNil decodes the first row into a new T, returning nil and
+// no error when no row matches.
+func QueryModelOrNil[T any](q Queryer, query string, args ...interface{}) (*T, error) {
+	return QueryModelOrNilContext[T](context.Background(), q, query, args...)
+}
+
+func QueryModelOrNilContext[T any](ctx context.Context, q Queryer, query string, args ...interface{}) (*T, error) {
+	model := new(T)
+	if err := q.QueryModelContext(ctx, query, model, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return model, nil
+}
+
+func orNil(rec Record, err error) (Record, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return rec, err
+}
@@ -0,0 +1,137 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlowQueryThreshold applies to connections without a threshold of their
+// own; zero turns slow query reporting off.
+var SlowQueryThreshold time.Duration
+
+// ExplainSlowQueries attaches the plan of slow queries that succeeded. The
+// statement is explained, not analyzed, so it is not run again.
+var ExplainSlowQueries bool
+
+type SlowQuery struct {
+	QueryEvent
+	Plan string
+}
+
+var (
+	slowThresholds = make(map[string]time.Duration)
+	slowCallbacks  []func(ctx context.Context, sq SlowQuery)
+	mSlow          sync.RWMutex
+)
+
+// OnSlowQuery registers fn to be called for statements running longer
+// than the threshold of their connection.
+func OnSlowQuery(fn func(ctx context.Context, sq SlowQuery)) {
+	mSlow.Lock()
+	slowCallbacks = append(slowCallbacks, fn)
+	mSlow.Unlock()
+}
+
+// SetSlowQueryThreshold overrides SlowQueryThreshold for a pool; a
+// negative threshold turns reporting off for it.
+func SetSlowQueryThreshold(connectionName string, threshold time.Duration) {
+	mSlow.Lock()
+	slowThresholds[connectionName] = threshold
+	mSlow.Unlock()
+}
+
+func watchSlowQueries() bool {
+	mSlow.RLock()
+	defer mSlow.RUnlock()
+	return len(slowCallbacks) > 0
+}
+
+func slowThreshold(connectionName string) time.Duration {
+	mSlow.RLock()
+	defer mSlow.RUnlock()
+	if threshold, found := slowThresholds[connectionName]; found {
+		return threshold
+	}
+	return SlowQueryThreshold
+}
+
+func (run *queryRun) reportSlow() {
+	threshold := slowThreshold(run.ev.ConnectionName)
+	if threshold <= 0 || run.ev.Duration < threshold {
+		return
+	}
+	mSlow.RLock()
+	callbacks := slowCallbacks
+	mSlow.RUnlock()
+	if len(callbacks) == 0 {
+		return
+	}
+
+	sq := SlowQuery{QueryEvent: run.ev}
+	if ExplainSlowQueries && run.ev.Err == nil {
+		plan, err := explain(run.q, run.ev.Driver, run.ev.Query, run.ev.Args)
+		if err != nil {
+			plan = fmt.Sprintf("spcdb: EXPLAIN failed: %v", err)
+		}
+		sq.Plan = plan
+	}
+	for _, fn := range callbacks {
+		fn(run.ctx, sq)
+	}
+}
+
+// explain bypasses runQuery so the plan is neither observed nor timed,
+// and the statement cache so plans don't push real statements out.
+func explain(q sqlQueryer, driverName, query string, args []interface{}) (string, error) {
+	if h, ok := q.(handle); ok {
+		if cached, ok := h.sqlQueryer.(cachedQueryer); ok {
+			h.sqlQueryer = cached.db
+			q = h
+		}
+		// A prepared statement would run itself instead of the EXPLAIN.
+		if h.prepared {
+			if h.owner == nil {
+				return "", fmt.Errorf("spcdb: Can't explain a prepared statement")
+			}
+			h.sqlQueryer, h.prepared = h.owner.DB, false
+			q = h
+		}
+	}
+	prefix := "EXPLAIN "
+	if isSQLite(driverName) {
+		prefix = "EXPLAIN QUERY PLAN "
+	}
+	rows, err := q.QueryContext(context.Background(), prefix+query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0)
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			fields[i] = fmt.Sprint(v)
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
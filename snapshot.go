@@ -0,0 +1,56 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SnapshotRead runs fn inside a read-only REPEATABLE READ transaction so
+// that every query made through q sees the same snapshot of the data.
+func (db *DB) SnapshotRead(fn func(q Queryer) error) error {
+	return db.snapshotRead("", fn)
+}
+
+// SnapshotReadAt is like SnapshotRead but imports a snapshot exported by
+// another transaction with ExportSnapshot (PostgreSQL only).
+func (db *DB) SnapshotReadAt(snapshotID string, fn func(q Queryer) error) error {
+	if snapshotID == "" {
+		return fmt.Errorf("spcdb: Empty snapshot id")
+	}
+	return db.snapshotRead(snapshotID, fn)
+}
+
+func (db *DB) snapshotRead(snapshotID string, fn func(q Queryer) error) error {
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if snapshotID != "" {
+		// SET TRANSACTION does not accept bind parameters.
+		quoted := "'" + strings.Replace(snapshotID, "'", "''", -1) + "'"
+		if _, err = tx.Exec("SET TRANSACTION SNAPSHOT " + quoted); err != nil {
+			return err
+		}
+	}
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExportSnapshot exports the snapshot of the transaction so that other
+// sessions can read the same data with SnapshotReadAt. The snapshot is
+// valid until tx ends.
+func (tx *Tx) ExportSnapshot() (string, error) {
+	var id string
+	err := tx.QueryRow("SELECT pg_export_snapshot()").Scan(&id)
+	return id, err
+}
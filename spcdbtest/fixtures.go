@@ -0,0 +1,176 @@
+package spcdbtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jenchik/spcdb"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFixtures replaces the contents of the tables described by the
+// .yml, .yaml and .json files of fsys. A file holds either a list of rows
+// for the table it is named after (users.yml), or a map of table names to
+// lists of rows. The tables are emptied children first and filled parents
+// first, following their foreign keys, in one transaction.
+func LoadFixtures(db *spcdb.DB, fsys fs.FS) error {
+	return LoadFixturesContext(context.Background(), db, fsys)
+}
+
+func LoadFixturesContext(ctx context.Context, db *spcdb.DB, fsys fs.FS) error {
+	fixtures, err := readFixtures(fsys)
+	if err != nil {
+		return err
+	}
+	order, err := fixtureOrder(ctx, db, fixtures)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = loadFixtures(tx, fixtures, order); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadFixtures(tx *spcdb.Tx, fixtures map[string][]map[string]interface{}, order []string) error {
+	for i := len(order) - 1; i >= 0; i-- {
+		if _, err := spcdb.Delete(order[i]).Unscoped().Exec(tx); err != nil {
+			return err
+		}
+	}
+	for _, table := range order {
+		for _, row := range fixtures[table] {
+			if _, err := spcdb.Insert(table).Values(spcdb.NewRecord(fixtureRow(row))).Exec(tx); err != nil {
+				return fmt.Errorf("spcdbtest: Fixture of '%s': %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func readFixtures(fsys fs.FS) (map[string][]map[string]interface{}, error) {
+	fixtures := make(map[string][]map[string]interface{})
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(name)
+		if ext != ".yml" && ext != ".yaml" && ext != ".json" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		var doc interface{}
+		if ext == ".json" {
+			err = json.Unmarshal(data, &doc)
+		} else {
+			err = yaml.Unmarshal(data, &doc)
+		}
+		if err != nil {
+			return fmt.Errorf("spcdbtest: Fixture %s: %w", name, err)
+		}
+
+		tables := map[string]interface{}{strings.TrimSuffix(path.Base(name), ext): doc}
+		if m, ok := doc.(map[string]interface{}); ok {
+			tables = m
+		}
+		for table, rows := range tables {
+			list, ok := rows.([]interface{})
+			if !ok {
+				return fmt.Errorf("spcdbtest: Fixture %s: rows of '%s' are not a list", name, table)
+			}
+			for _, row := range list {
+				m, ok := row.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("spcdbtest: Fixture %s: row of '%s' is not a map", name, table)
+				}
+				fixtures[table] = append(fixtures[table], m)
+			}
+			if _, found := fixtures[table]; !found {
+				fixtures[table] = nil
+			}
+		}
+		return nil
+	})
+	return fixtures, err
+}
+
+// fixtureRow encodes nested maps as JSON, for json columns, and makes
+// whole JSON numbers integers.
+func fixtureRow(row map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(row))
+	for key, value := range row {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			data, _ := json.Marshal(v)
+			value = string(data)
+		case float64:
+			if v == float64(int64(v)) {
+				value = int64(v)
+			}
+		}
+		ret[key] = value
+	}
+	return ret
+}
+
+// fixtureOrder sorts the tables so that referenced ones come first.
+func fixtureOrder(ctx context.Context, db *spcdb.DB, fixtures map[string][]map[string]interface{}) ([]string, error) {
+	deps := make(map[string][]string, len(fixtures))
+	for table := range fixtures {
+		keys, err := db.ForeignKeysContext(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		for _, fk := range keys {
+			if _, found := fixtures[fk.RefTable]; found && fk.RefTable != table {
+				deps[table] = append(deps[table], fk.RefTable)
+			}
+		}
+	}
+
+	tables := make([]string, 0, len(fixtures))
+	for table := range fixtures {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	order := make([]string, 0, len(tables))
+	state := make(map[string]int, len(tables))
+	var visit func(table string) error
+	visit = func(table string) error {
+		switch state[table] {
+		case 1:
+			return fmt.Errorf("spcdbtest: Foreign key cycle through '%s'", table)
+		case 2:
+			return nil
+		}
+		state[table] = 1
+		for _, dep := range deps[table] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[table] = 2
+		order = append(order, table)
+		return nil
+	}
+	for _, table := range tables {
+		if err := visit(table); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,99 @@
+package spcdbtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenchik/spcdb"
+)
+
+// keyStore is a fake driver keeping the ids of the rows of each table,
+// enough to tell a soft DELETE from a real one by the INSERTs that follow.
+type keyStore struct {
+	m    sync.Mutex
+	rows map[string]map[interface{}]bool
+}
+
+type keyConn struct{ store *keyStore }
+type keyStmt struct {
+	store *keyStore
+	query string
+}
+
+func (s *keyStore) Open(string) (driver.Conn, error) { return keyConn{s}, nil }
+
+func (c keyConn) Prepare(query string) (driver.Stmt, error) { return keyStmt{c.store, query}, nil }
+func (c keyConn) Close() error                              { return nil }
+func (c keyConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c keyConn) Commit() error                             { return nil }
+func (c keyConn) Rollback() error                           { return nil }
+
+func (s keyStmt) Close() error  { return nil }
+func (s keyStmt) NumInput() int { return -1 }
+
+func (s keyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	fields := strings.Fields(strings.NewReplacer(`"`, "", "`", "").Replace(s.query))
+	s.store.m.Lock()
+	defer s.store.m.Unlock()
+	switch {
+	case fields[0] == "DELETE":
+		delete(s.store.rows, fields[2])
+	case fields[0] == "INSERT":
+		table := fields[2]
+		if s.store.rows[table][args[0]] {
+			return nil, fmt.Errorf("duplicate key %v in %s", args[0], table)
+		}
+		if s.store.rows[table] == nil {
+			s.store.rows[table] = make(map[interface{}]bool)
+		}
+		s.store.rows[table][args[0]] = true
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s keyStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("keyStore: no queries")
+}
+
+type softUser struct {
+	ID        int64      `mapstructure:"id"`
+	DeletedAt *time.Time `mapstructure:"deleted_at" spcdb:"softdelete"`
+}
+
+func TestLoadFixturesSoftDelete(t *testing.T) {
+	store := &keyStore{rows: map[string]map[interface{}]bool{
+		"soft_users": {int64(1): true},
+	}}
+	sql.Register("spcdbtest_keystore", store)
+	if err := spcdb.RegisterModel("soft_users", softUser{}); err != nil {
+		t.Fatal(err)
+	}
+	db, err := spcdb.Open("spcdbtest_keystore", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := map[string][]map[string]interface{}{
+		"soft_users": {{"id": int64(1)}},
+	}
+	if err = loadFixtures(tx, fixtures, []string{"soft_users"}); err != nil {
+		t.Fatalf("loadFixtures: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if !store.rows["soft_users"][int64(1)] {
+		t.Fatalf("fixture row missing: %v", store.rows)
+	}
+}
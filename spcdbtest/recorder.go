@@ -0,0 +1,130 @@
+package spcdbtest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenchik/spcdb"
+)
+
+// Statement is a statement run by the spcdb helpers while recording.
+type Statement struct {
+	ConnectionName string
+	Query          string
+	Args           []interface{}
+	Duration       time.Duration
+	Rows           int64
+	Err            error
+}
+
+var leadingComments = regexp.MustCompile(`^(\s*/\*.*?\*/)*\s*`)
+
+// Verb is the upper-cased first keyword of the statement, e.g. "UPDATE".
+func (s Statement) Verb() string {
+	fields := strings.Fields(leadingComments.ReplaceAllString(s.Query, ""))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+var tableRe = regexp.MustCompile("(?i)^(?:UPDATE|INSERT\\s+INTO|DELETE\\s+FROM|SELECT\\s.*?\\sFROM)\\s+[\"`\\[]?([\\w.]+)")
+
+// Table is the table the statement writes to, or the first one it reads
+// from, unquoted; empty when it cannot be told.
+func (s Statement) Table() string {
+	query := strings.Join(strings.Fields(leadingComments.ReplaceAllString(s.Query, "")), " ")
+	m := tableRe.FindStringSubmatch(query)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// Recorder collects the statements run by the spcdb helpers. Recorders see
+// the statements of every connection, tests running in parallel included.
+type Recorder struct {
+	m     sync.Mutex
+	stmts []Statement
+}
+
+var (
+	recorders    = make(map[*Recorder]bool)
+	mRecorders   sync.Mutex
+	recorderHook sync.Once
+)
+
+// NewRecorder starts recording until the test ends, or until Stop when t
+// is nil.
+func NewRecorder(t testing.TB) *Recorder {
+	recorderHook.Do(func() {
+		spcdb.ObserveQueries(recordStatement)
+	})
+	r := &Recorder{}
+	mRecorders.Lock()
+	recorders[r] = true
+	mRecorders.Unlock()
+	if t != nil {
+		t.Cleanup(r.Stop)
+	}
+	return r
+}
+
+func recordStatement(ctx context.Context, ev spcdb.QueryEvent) {
+	stmt := Statement{
+		ConnectionName: ev.ConnectionName,
+		Query:          ev.Query,
+		Args:           ev.Args,
+		Duration:       ev.Duration,
+		Rows:           ev.Rows,
+		Err:            ev.Err,
+	}
+	mRecorders.Lock()
+	defer mRecorders.Unlock()
+	for r := range recorders {
+		r.m.Lock()
+		r.stmts = append(r.stmts, stmt)
+		r.m.Unlock()
+	}
+}
+
+func (r *Recorder) Stop() {
+	mRecorders.Lock()
+	delete(recorders, r)
+	mRecorders.Unlock()
+}
+
+func (r *Recorder) Reset() {
+	r.m.Lock()
+	r.stmts = nil
+	r.m.Unlock()
+}
+
+// Statements returns the recorded statements in the order they finished.
+func (r *Recorder) Statements() []Statement {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([]Statement{}, r.stmts...)
+}
+
+func (r *Recorder) Filter(fn func(Statement) bool) []Statement {
+	ret := make([]Statement, 0)
+	for _, stmt := range r.Statements() {
+		if fn(stmt) {
+			ret = append(ret, stmt)
+		}
+	}
+	return ret
+}
+
+// Count is the number of statements with the verb on the table, e.g.
+// Count("UPDATE", "users"); an empty table matches any.
+func (r *Recorder) Count(verb, table string) int {
+	return len(r.Filter(func(stmt Statement) bool {
+		return strings.EqualFold(stmt.Verb(), verb) && (table == "" || stmt.Table() == table)
+	}))
+}
@@ -0,0 +1,24 @@
+package spcdbtest
+
+import (
+	"testing"
+
+	"github.com/jenchik/spcdb"
+)
+
+// WithRollback runs fn in a transaction that is rolled back once fn
+// returns, even if it fails the test, so every test sees the database as
+// it was seeded.
+func WithRollback(t testing.TB, db *spcdb.DB, fn func(tx spcdb.Queryer)) {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("spcdbtest: Begin: %v", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			t.Errorf("spcdbtest: Rollback: %v", err)
+		}
+	}()
+	fn(tx)
+}
@@ -0,0 +1,314 @@
+// Package spcdbtest helps unit testing code written against
+// spcdb.Queryer without a database. A MockDB answers the queries it is
+// told to expect, in order, with canned Records, results or errors:
+//
+//	db := spcdbtest.New()
+//	db.ExpectQuery("SELECT id, name FROM users WHERE id = $1").WithArgs(1).
+//		WillReturnRecords(spcdbtest.NewRecord("id", 1, "name", "Ann"))
+//	user, err := repo.FindUser(db, 1)
+//	if err := db.ExpectationsWereMet(); err != nil { ... }
+package spcdbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jenchik/spcdb"
+)
+
+// ErrRows is returned by Query and QueryContext: a MockDB has no driver to
+// produce *sql.Rows, only Records.
+var ErrRows = errors.New("spcdbtest: Raw rows are not supported, use the Record helpers")
+
+type Expectation struct {
+	exec     bool
+	query    string
+	args     []interface{}
+	anyArgs  bool
+	records  []spcdb.Record
+	result   sql.Result
+	err      error
+	consumed bool
+}
+
+// WithArgs makes the expectation match only these arguments; any are
+// accepted otherwise.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.anyArgs = false
+	return e
+}
+
+func (e *Expectation) WillReturnRecords(recs ...spcdb.Record) *Expectation {
+	e.records = recs
+	return e
+}
+
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = Result{LastID: lastInsertID, Affected: rowsAffected}
+	return e
+}
+
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	kind := "query"
+	if e.exec {
+		kind = "exec"
+	}
+	if e.anyArgs {
+		return fmt.Sprintf("%s '%s'", kind, e.query)
+	}
+	return fmt.Sprintf("%s '%s' with args %v", kind, e.query, e.args)
+}
+
+type Result struct {
+	LastID   int64
+	Affected int64
+}
+
+func (r Result) LastInsertId() (int64, error) {
+	return r.LastID, nil
+}
+
+func (r Result) RowsAffected() (int64, error) {
+	return r.Affected, nil
+}
+
+// MockDB implements spcdb.Queryer. Queries are compared with the expected
+// ones after collapsing whitespace.
+type MockDB struct {
+	// Driver is reported by DriverName, "postgres" by default, and decides
+	// the placeholders the query builders render.
+	Driver string
+
+	m        sync.Mutex
+	expected []*Expectation
+}
+
+var _ spcdb.Queryer = (*MockDB)(nil)
+
+func New() *MockDB {
+	return &MockDB{Driver: "postgres"}
+}
+
+// ExpectQuery expects a query of the Query* and Exists* helpers.
+func (db *MockDB) ExpectQuery(query string) *Expectation {
+	return db.expect(false, query)
+}
+
+// ExpectExec expects a statement run through Exec.
+func (db *MockDB) ExpectExec(query string) *Expectation {
+	return db.expect(true, query)
+}
+
+func (db *MockDB) expect(exec bool, query string) *Expectation {
+	e := &Expectation{exec: exec, query: normalize(query), anyArgs: true}
+	db.m.Lock()
+	db.expected = append(db.expected, e)
+	db.m.Unlock()
+	return e
+}
+
+// ExpectationsWereMet reports the expectations left unconsumed.
+func (db *MockDB) ExpectationsWereMet() error {
+	db.m.Lock()
+	defer db.m.Unlock()
+	for _, e := range db.expected {
+		if !e.consumed {
+			return fmt.Errorf("spcdbtest: Expected %s was not run", e)
+		}
+	}
+	return nil
+}
+
+// next consumes the first pending expectation, which must match.
+func (db *MockDB) next(exec bool, query string, args []interface{}) (*Expectation, error) {
+	db.m.Lock()
+	defer db.m.Unlock()
+	query = normalize(query)
+	for _, e := range db.expected {
+		if e.consumed {
+			continue
+		}
+		if e.exec != exec || e.query != query || (!e.anyArgs && !reflect.DeepEqual(e.args, normalizeArgs(args))) {
+			return nil, fmt.Errorf("spcdbtest: Unexpected query '%s' with args %v, expected %s", query, args, e)
+		}
+		e.consumed = true
+		return e, e.err
+	}
+	return nil, fmt.Errorf("spcdbtest: Unexpected query '%s' with args %v", query, args)
+}
+
+func (db *MockDB) records(query string, args []interface{}) ([]spcdb.Record, error) {
+	e, err := db.next(false, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return e.records, nil
+}
+
+func (db *MockDB) DriverName() string {
+	return db.Driver
+}
+
+func (db *MockDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, ErrRows
+}
+
+func (db *MockDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, ErrRows
+}
+
+func (db *MockDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *MockDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e, err := db.next(true, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return Result{}, nil
+	}
+	return e.result, nil
+}
+
+func (db *MockDB) Exists(query string, args ...interface{}) (bool, error) {
+	recs, err := db.records(query, args)
+	return len(recs) > 0, err
+}
+
+func (db *MockDB) ExistsRecord(query string, args ...interface{}) error {
+	return db.ExistsRecordContext(context.Background(), query, args...)
+}
+
+func (db *MockDB) ExistsRecordContext(ctx context.Context, query string, args ...interface{}) error {
+	recs, err := db.records(query, args)
+	if err == nil && len(recs) == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+func (db *MockDB) QueryRecords(query string, args ...interface{}) ([]spcdb.Record, error) {
+	return db.QueryRecordsContext(context.Background(), query, args...)
+}
+
+func (db *MockDB) QueryRecordsContext(ctx context.Context, query string, args ...interface{}) ([]spcdb.Record, error) {
+	recs, err := db.records(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return append([]spcdb.Record{}, recs...), nil
+}
+
+func (db *MockDB) QueryRecord(query string, args ...interface{}) (spcdb.Record, error) {
+	return db.QueryRecordContext(context.Background(), query, args...)
+}
+
+func (db *MockDB) QueryRecordContext(ctx context.Context, query string, args ...interface{}) (spcdb.Record, error) {
+	recs, err := db.records(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return recs[0], nil
+}
+
+func (db *MockDB) QueryModel(query string, model interface{}, args ...interface{}) error {
+	return db.QueryModelContext(context.Background(), query, model, args...)
+}
+
+func (db *MockDB) QueryModelContext(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	rec, err := db.QueryRecordContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return rec.Model(model)
+}
+
+func (db *MockDB) QueryModels(query string, dest interface{}, args ...interface{}) error {
+	return db.QueryModelsContext(context.Background(), query, dest, args...)
+}
+
+func (db *MockDB) QueryModelsContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("spcdbtest: QueryModels needs a pointer to a slice, got %T", dest)
+	}
+	recs, err := db.records(query, args)
+	if err != nil {
+		return err
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	ret := reflect.MakeSlice(slice.Type(), 0, len(recs))
+	for _, rec := range recs {
+		if elemType.Kind() == reflect.Ptr {
+			elem := reflect.New(elemType.Elem())
+			if err = rec.Model(elem.Interface()); err != nil {
+				return err
+			}
+			ret = reflect.Append(ret, elem)
+			continue
+		}
+		elem := reflect.New(elemType)
+		if err = rec.Model(elem.Interface()); err != nil {
+			return err
+		}
+		ret = reflect.Append(ret, elem.Elem())
+	}
+	slice.Set(ret)
+	return nil
+}
+
+// NewRecord builds a Record from alternating keys and values:
+//
+//	spcdbtest.NewRecord("id", 1, "name", "Ann")
+func NewRecord(keyValues ...interface{}) spcdb.Record {
+	if len(keyValues)%2 != 0 {
+		panic("spcdbtest: NewRecord needs key/value pairs")
+	}
+	m := make(map[string]interface{}, len(keyValues)/2)
+	for i := 0; i < len(keyValues); i += 2 {
+		key, ok := keyValues[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("spcdbtest: NewRecord key %v is not a string", keyValues[i]))
+		}
+		m[key] = keyValues[i+1]
+	}
+	return spcdb.NewRecord(m)
+}
+
+// Records builds Records from map literals.
+func Records(maps ...map[string]interface{}) []spcdb.Record {
+	recs := make([]spcdb.Record, len(maps))
+	for i, m := range maps {
+		recs[i] = spcdb.NewRecord(m)
+	}
+	return recs
+}
+
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// normalizeArgs lets WithArgs(nil) match a call without arguments.
+func normalizeArgs(args []interface{}) []interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
@@ -0,0 +1,43 @@
+package spcdb
+
+import "strings"
+
+func isSQLite(driverName string) bool {
+	return driverName == "sqlite3" || driverName == "sqlite"
+}
+
+// sqliteDefaults keeps a private in-memory database on one connection;
+// every new connection would otherwise get an empty database of its own.
+func sqliteDefaults(db *DB, dataSourceName string) {
+	if !isSQLite(db.driver) {
+		return
+	}
+	if strings.Contains(dataSourceName, ":memory:") && !strings.Contains(dataSourceName, "cache=shared") {
+		db.SetMaxOpenConns(1)
+	}
+}
+
+// decodeDynamicColumn maps the values of databases with dynamic typing,
+// SQLite foremost, onto the column's declared type.
+func decodeDynamicColumn(dbType string, value interface{}) (interface{}, bool) {
+	switch dbType {
+	case "BOOLEAN", "BOOL":
+		if n, ok := value.(int64); ok {
+			return n != 0, true
+		}
+	case "DATETIME", "TIMESTAMP", "DATE":
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			return nil, false
+		}
+		if t, err := parseTime(s); err == nil {
+			return t, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,113 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Stmt is a prepared statement with the helpers of DB and Tx, minus the
+// query text.
+type Stmt struct {
+	*sql.Stmt
+	query  string
+	driver string
+	name   string
+	inTx   bool
+	owner  *DB
+}
+
+func (db *DB) PrepareStmt(query string) (*Stmt, error) {
+	return db.PrepareStmtContext(context.Background(), query)
+}
+
+func (db *DB) PrepareStmtContext(ctx context.Context, query string) (*Stmt, error) {
+	stmt, err := db.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: stmt, query: query, driver: db.driver, name: db.name, owner: db}, nil
+}
+
+func (tx *Tx) PrepareStmt(query string) (*Stmt, error) {
+	return tx.PrepareStmtContext(context.Background(), query)
+}
+
+func (tx *Tx) PrepareStmtContext(ctx context.Context, query string) (*Stmt, error) {
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: stmt, query: query, driver: tx.driver, name: tx.name, inTx: true, owner: tx.db}, nil
+}
+
+// stmtQueryer runs its statement for whatever query it is given.
+type stmtQueryer struct {
+	stmt *sql.Stmt
+}
+
+func (q stmtQueryer) QueryContext(ctx context.Context, _ string, args ...interface{}) (*sql.Rows, error) {
+	return q.stmt.QueryContext(ctx, args...)
+}
+
+func (q stmtQueryer) ExecContext(ctx context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	return q.stmt.ExecContext(ctx, args...)
+}
+
+func (s *Stmt) queryer() sqlQueryer {
+	return handle{
+		sqlQueryer: stmtQueryer{s.Stmt},
+		driver:     s.driver,
+		name:       s.name,
+		inTx:       s.inTx,
+		owner:      s.owner,
+		prepared:   true,
+	}
+}
+
+func (s *Stmt) Exec(args ...interface{}) (sql.Result, error) {
+	return runExec(context.Background(), s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	return runExec(ctx, s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) Exists(args ...interface{}) (bool, error) {
+	return exists(context.Background(), s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) QueryModel(model interface{}, args ...interface{}) error {
+	return queryModel(context.Background(), s.queryer(), s.query, model, args...)
+}
+
+func (s *Stmt) QueryModels(dest interface{}, args ...interface{}) error {
+	return queryModels(context.Background(), s.queryer(), s.query, dest, args...)
+}
+
+func (s *Stmt) QueryRecords(args ...interface{}) ([]Record, error) {
+	return queryRecords(context.Background(), s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) QueryRecord(args ...interface{}) (Record, error) {
+	return queryRecord(context.Background(), s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) ExistsContext(ctx context.Context, args ...interface{}) (bool, error) {
+	return exists(ctx, s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) QueryModelContext(ctx context.Context, model interface{}, args ...interface{}) error {
+	return queryModel(ctx, s.queryer(), s.query, model, args...)
+}
+
+func (s *Stmt) QueryModelsContext(ctx context.Context, dest interface{}, args ...interface{}) error {
+	return queryModels(ctx, s.queryer(), s.query, dest, args...)
+}
+
+func (s *Stmt) QueryRecordsContext(ctx context.Context, args ...interface{}) ([]Record, error) {
+	return queryRecords(ctx, s.queryer(), s.query, args...)
+}
+
+func (s *Stmt) QueryRecordContext(ctx context.Context, args ...interface{}) (Record, error) {
+	return queryRecord(ctx, s.queryer(), s.query, args...)
+}
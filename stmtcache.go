@@ -0,0 +1,176 @@
+package spcdb
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// StmtCacheSize is the statement cache size given to newly opened DBs;
+// zero disables caching.
+var StmtCacheSize = 0
+
+type StmtCacheStats struct {
+	Size   int
+	Hits   uint64
+	Misses uint64
+}
+
+type stmtEntry struct {
+	query string
+	stmt  *sql.Stmt
+	// refs counts the callers between get and release; an evicted entry
+	// is closed once it drops to zero.
+	refs    int
+	evicted bool
+}
+
+// stmtCache keeps the most recently used prepared statements of a DB.
+type stmtCache struct {
+	size   int
+	order  *list.List
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+	m      sync.Mutex
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the entry of the query, preparing it if needed; the caller
+// must release it when done with the statement.
+func (c *stmtCache) get(ctx context.Context, db *sql.DB, query string) (*stmtEntry, error) {
+	c.m.Lock()
+	if el, found := c.items[query]; found {
+		c.hits++
+		c.order.MoveToFront(el)
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		c.m.Unlock()
+		return entry, nil
+	}
+	c.misses++
+	c.m.Unlock()
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	if el, found := c.items[query]; found {
+		// Prepared concurrently by someone else.
+		stmt.Close()
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		return entry, nil
+	}
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.evict(c.order.Back())
+	}
+	return entry, nil
+}
+
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// evict drops the entry, closing its statement unless it is still in use.
+func (c *stmtCache) evict(el *list.Element) {
+	entry := c.order.Remove(el).(*stmtEntry)
+	delete(c.items, entry.query)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+func (c *stmtCache) resize(size int) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.size = size
+	for c.order.Len() > c.size {
+		c.evict(c.order.Back())
+	}
+}
+
+func (c *stmtCache) stats() StmtCacheStats {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return StmtCacheStats{Size: c.order.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+// cachedQueryer runs queries of a DB through its statement cache.
+type cachedQueryer struct {
+	db    *sql.DB
+	cache *stmtCache
+}
+
+func (q cachedQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	entry, err := q.cache.get(ctx, q.db, query)
+	if err != nil {
+		return nil, err
+	}
+	// The rows keep the statement from closing until they are closed.
+	defer q.cache.release(entry)
+	return entry.stmt.QueryContext(ctx, args...)
+}
+
+func (q cachedQueryer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return q.db.ExecContext(ctx, query, args...)
+}
+
+// SetStmtCacheSize changes how many prepared statements the DB keeps;
+// zero disables the cache and closes the cached statements.
+func (db *DB) SetStmtCacheSize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	if db.stmts == nil {
+		db.stmts = newStmtCache(size)
+		return
+	}
+	db.stmts.resize(size)
+}
+
+func (db *DB) StmtCacheStats() StmtCacheStats {
+	if db.stmts == nil {
+		return StmtCacheStats{}
+	}
+	return db.stmts.stats()
+}
+
+func (db *DB) queryer() sqlQueryer {
+	enabled := false
+	if db.stmts != nil {
+		db.stmts.m.Lock()
+		enabled = db.stmts.size > 0
+		db.stmts.m.Unlock()
+	}
+	if !enabled {
+		return handle{sqlQueryer: db.DB, driver: db.driver, name: db.name, owner: db}
+	}
+	return handle{sqlQueryer: cachedQueryer{db.DB, db.stmts}, driver: db.driver, name: db.name, owner: db}
+}
+
+func (db *DB) Close() error {
+	if db.stmts != nil {
+		db.stmts.resize(0)
+	}
+	return db.DB.Close()
+}
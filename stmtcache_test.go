@@ -0,0 +1,25 @@
+package spcdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStmtCacheHitsWithDeadlines(t *testing.T) {
+	db := openNop(t)
+	db.SetStmtCacheSize(4)
+	hints := DeadlineHints
+	DeadlineHints = DeadlineHintComment
+	defer func() { DeadlineHints = hints }()
+
+	for i := 1; i <= 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i)*time.Minute)
+		// The nop driver has no rows; only the preparing matters.
+		db.QueryRecordsContext(ctx, "SELECT id FROM cached WHERE id = ?", i)
+		cancel()
+	}
+	if stats := db.StmtCacheStats(); stats.Misses != 1 || stats.Hits != 2 || stats.Size != 1 {
+		t.Errorf("stats = %+v, want 1 miss, 2 hits and 1 statement", stats)
+	}
+}
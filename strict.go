@@ -0,0 +1,102 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// StrictDecode makes Model decoding fail when a result column has no
+// struct field or a struct field receives no column, which catches typos
+// in SELECT lists. Fields tagged `spcdb:"optional"` may go without one.
+// Options.StrictDecode overrides it per DB.
+var StrictDecode = false
+
+type strictDecodeKey struct{}
+
+// WithStrictDecode enables strict decoding for the queries run with the
+// returned context only.
+func WithStrictDecode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictDecodeKey{}, true)
+}
+
+func strictFrom(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictDecodeKey{}).(bool)
+	return strict
+}
+
+// DecodeError lists the mismatches found by strict decoding. Nested fields
+// are named by their path, e.g. "author.id".
+type DecodeError struct {
+	Type          string
+	UnusedColumns []string
+	UnsetFields   []string
+}
+
+func (e *DecodeError) Error() string {
+	var problems []string
+	if len(e.UnusedColumns) > 0 {
+		problems = append(problems, fmt.Sprintf("columns without field: %s", strings.Join(e.UnusedColumns, ", ")))
+	}
+	if len(e.UnsetFields) > 0 {
+		problems = append(problems, fmt.Sprintf("fields without column: %s", strings.Join(e.UnsetFields, ", ")))
+	}
+	return fmt.Sprintf("spcdb: Decoding into %s: %s", e.Type, strings.Join(problems, "; "))
+}
+
+// checkDecoded reports the mismatches in md as a DecodeError when strict,
+// else as warnings, if collected.
+func checkDecoded(dst interface{}, md *mapstructure.Metadata, opts decodeOptions) error {
+	typ := reflect.TypeOf(dst)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	unset := make([]string, 0, len(md.Unset))
+	for _, name := range md.Unset {
+		if !optionalField(typ, opts.tag(), name) {
+			unset = append(unset, name)
+		}
+	}
+	if len(md.Unused) == 0 && len(unset) == 0 {
+		return nil
+	}
+	sort.Strings(md.Unused)
+	sort.Strings(unset)
+
+	if !opts.strict {
+		for _, col := range md.Unused {
+			opts.warnings.add(WarnUnusedColumn, col, "spcdb: Column '%s' has no field in %s", col, typ)
+		}
+		for _, name := range unset {
+			opts.warnings.add(WarnUnsetField, name, "spcdb: Field '%s' of %s has no column", name, typ)
+		}
+		return nil
+	}
+	return &DecodeError{Type: fmt.Sprint(typ), UnusedColumns: md.Unused, UnsetFields: unset}
+}
+
+// optionalField tells whether the field at the dotted path is tagged
+// optional, or lies in a struct field that is.
+func optionalField(typ reflect.Type, tag, path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		if typ == nil || typ.Kind() != reflect.Struct {
+			return false
+		}
+		field, found := structInfoFor(typ, tag).byName[name]
+		if !found {
+			return false
+		}
+		if _, found := field.options["optional"]; found {
+			return true
+		}
+		typ = typ.FieldByIndex(field.index).Type
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package spcdb
+
+import (
+	"reflect"
+	"sync"
+	"unicode"
+)
+
+type fieldInfo struct {
+	// index is the path to the field through embedded structs.
+	index   []int
+	name    string
+	options map[string]string
+}
+
+type structInfo struct {
+	fields []*fieldInfo
+	byName map[string]*fieldInfo
+}
+
+// structInfos caches struct metadata by reflect.Type and tag name, as the
+// tag name can change at runtime.
+var structInfos sync.Map
+
+type structInfoKey struct {
+	typ reflect.Type
+	tag string
+}
+
+func structInfoFor(typ reflect.Type, tag string) *structInfo {
+	key := structInfoKey{typ, tag}
+	if info, found := structInfos.Load(key); found {
+		return info.(*structInfo)
+	}
+	info := &structInfo{byName: make(map[string]*fieldInfo)}
+	collectFields(typ, tag, nil, info)
+	actual, _ := structInfos.LoadOrStore(key, info)
+	return actual.(*structInfo)
+}
+
+// collectFields walks the fields the way recFromStruct always did, with
+// fields of embedded structs first so that the outer ones shadow them.
+func collectFields(typ reflect.Type, tag string, index []int, info *structInfo) {
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		if !typeField.Anonymous || typeField.Tag.Get(tag) != "" {
+			continue
+		}
+		embedded := typeField.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if embedded.Kind() == reflect.Struct {
+			collectFields(embedded, tag, appendIndex(index, i), info)
+		}
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		if !unicode.IsUpper(rune(typeField.Name[0])) {
+			continue
+		}
+
+		recName := typeField.Tag.Get(tag)
+		if recName == "" {
+			if typeField.Anonymous {
+				continue
+			}
+			recName = typeField.Name
+		} else if recName == "-" {
+			continue
+		}
+
+		field := &fieldInfo{
+			index:   appendIndex(index, i),
+			name:    recName,
+			options: tagOptions(typeField),
+		}
+		if prev, found := info.byName[recName]; found {
+			for j, f := range info.fields {
+				if f == prev {
+					info.fields = append(info.fields[:j], info.fields[j+1:]...)
+					break
+				}
+			}
+		}
+		info.fields = append(info.fields, field)
+		info.byName[recName] = field
+	}
+}
+
+func appendIndex(index []int, i int) []int {
+	ret := make([]int, len(index)+1)
+	copy(ret, index)
+	ret[len(index)] = i
+	return ret
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex stopping at nil embedded
+// pointers instead of panicking.
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
@@ -0,0 +1,65 @@
+package spcdb
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// defaultTimeLayouts are tried in order, after the time format, when a
+// string is decoded into a time.Time field.
+var defaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeHook decodes time.Time fields in the time settings of conf.
+func timeHook(conf *config) DecodeHook {
+	if conf == nil {
+		conf = defaultConfig
+	}
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != timeType {
+			return data, nil
+		}
+		switch v := data.(type) {
+		case time.Time:
+			return conf.inLocation(v), nil
+		case string:
+			return conf.parseTime(v)
+		case []byte:
+			return conf.parseTime(string(v))
+		}
+		return data, nil
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	return defaultConfig.parseTime(s)
+}
+
+func (c *config) parseTime(s string) (time.Time, error) {
+	loc := c.timeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	layouts := append([]string{c.timeFormat}, c.timeLayouts...)
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return c.inLocation(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("spcdb: Cannot parse '%s' as time", s)
+}
+
+func (c *config) inLocation(t time.Time) time.Time {
+	if c.timeLocation == nil {
+		return t
+	}
+	return t.In(c.timeLocation)
+}
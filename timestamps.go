@@ -0,0 +1,110 @@
+package spcdb
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ServerTimestamps makes the insert and update helpers fill automatic
+// timestamps with CURRENT_TIMESTAMP instead of the client clock, which
+// also leaves the fields of models untouched.
+var ServerTimestamps = false
+
+// serverNow stands for CURRENT_TIMESTAMP in rendered statements.
+type serverNow struct{}
+
+// timestampColumns finds the columns of the fields tagged
+// "autocreatetime" and "autoupdatetime" in the model, or the registered
+// one of the table, falling back to created_at and updated_at fields.
+func timestampColumns(table string, model interface{}, tag string) (string, string) {
+	var typ reflect.Type
+	if model != nil {
+		typ = reflect.TypeOf(model)
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	} else if tm := getTableModel(table); tm != nil {
+		typ = tm.typ
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return "", ""
+	}
+
+	var created, updated string
+	info := structInfoFor(typ, tag)
+	for _, field := range info.fields {
+		if _, found := field.options["autocreatetime"]; found {
+			created = field.name
+		}
+		if _, found := field.options["autoupdatetime"]; found {
+			updated = field.name
+		}
+	}
+	if created == "" && info.byName["created_at"] != nil {
+		created = "created_at"
+	}
+	if updated == "" && info.byName["updated_at"] != nil {
+		updated = "updated_at"
+	}
+	return created, updated
+}
+
+// autoTimestamps returns the timestamp columns to set: on insert those the
+// values leave empty, on update the update column.
+func autoTimestamps(table string, model interface{}, values Record, insert bool, tag string) map[string]interface{} {
+	created, updated := timestampColumns(table, model, tag)
+	if created == "" && updated == "" {
+		return nil
+	}
+	var now interface{} = time.Now()
+	if ServerTimestamps {
+		now = serverNow{}
+	}
+	stamps := make(map[string]interface{}, 2)
+	if insert && created != "" && isZeroValue(values.Get(created)) {
+		stamps[created] = now
+	}
+	if updated != "" && (!insert || isZeroValue(values.Get(updated))) {
+		stamps[updated] = now
+	}
+	return stamps
+}
+
+// stampModel copies client side timestamps into the model fields.
+func stampModel(model interface{}, stamps map[string]interface{}, tag string) {
+	val := reflect.ValueOf(model)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return
+	}
+	val = val.Elem()
+	info := structInfoFor(val.Type(), tag)
+	for col, stamp := range stamps {
+		now, ok := stamp.(time.Time)
+		field := info.byName[col]
+		if !ok || field == nil {
+			continue
+		}
+		if f, ok := fieldByIndex(val, field.index); ok && f.CanSet() {
+			newModel(now, f.Addr().Interface())
+		}
+	}
+}
+
+// stampedColumns adds the timestamp columns to the sorted cols.
+func stampedColumns(cols []string, stamps map[string]interface{}) []string {
+	if len(stamps) == 0 {
+		return cols
+	}
+	for col := range stamps {
+		if i := sort.SearchStrings(cols, col); i == len(cols) || cols[i] != col {
+			cols = append(cols, col)
+			sort.Strings(cols)
+		}
+	}
+	return cols
+}
+
+func isZeroValue(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
@@ -0,0 +1,116 @@
+// Package tracing creates OpenTelemetry spans for the statements run by
+// the spcdb helpers.
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/jenchik/spcdb"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/jenchik/spcdb"
+
+type Options struct {
+	// TracerProvider defaults to the global one.
+	TracerProvider trace.TracerProvider
+	// Sanitize rewrites the statement recorded as db.statement; nil keeps
+	// it as is. SanitizeQuery replaces literals with '?'.
+	Sanitize func(query string) string
+	// OmitStatement leaves db.statement out altogether.
+	OmitStatement bool
+}
+
+// Register makes every statement produce a client span, child of the span
+// found in the context the statement runs with.
+func Register(opts Options) {
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	spcdb.AddQueryHook(&hook{
+		tracer: provider.Tracer(instrumentationName),
+		opts:   opts,
+	})
+}
+
+var (
+	quotedLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+func SanitizeQuery(query string) string {
+	query = quotedLiteral.ReplaceAllString(query, "?")
+	return numericLiteral.ReplaceAllString(query, "?")
+}
+
+type spanKey struct{}
+
+type hook struct {
+	tracer trace.Tracer
+	opts   Options
+}
+
+func (h *hook) BeforeQuery(ctx context.Context, ev *spcdb.QueryEvent) context.Context {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", dbSystem(ev.Driver)),
+		attribute.String("db.spcdb.connection", ev.ConnectionName),
+	}
+	if !h.opts.OmitStatement {
+		statement := ev.Query
+		if h.opts.Sanitize != nil {
+			statement = h.opts.Sanitize(statement)
+		}
+		attrs = append(attrs, attribute.String("db.statement", statement))
+	}
+	ctx, span := h.tracer.Start(ctx, spanName(ev.Query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(ev.Start),
+		trace.WithAttributes(attrs...),
+	)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (h *hook) AfterQuery(ctx context.Context, ev *spcdb.QueryEvent) {
+	span, ok := ctx.Value(spanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if ev.Rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows", ev.Rows))
+	}
+	if ev.Long {
+		span.SetAttributes(attribute.Bool("db.spcdb.long", true))
+	}
+	if ev.Err != nil {
+		span.RecordError(ev.Err)
+		span.SetStatus(codes.Error, ev.Err.Error())
+	}
+	span.End(trace.WithTimestamp(ev.Start.Add(ev.Duration)))
+}
+
+// spanName is the SQL verb, low cardinality unlike the statement.
+func spanName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "spcdb"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func dbSystem(driverName string) string {
+	switch driverName {
+	case "postgres", "pgx":
+		return "postgresql"
+	case "sqlite3", "sqlite":
+		return "sqlite"
+	case "sqlserver", "mssql":
+		return "mssql"
+	}
+	return driverName
+}
@@ -0,0 +1,59 @@
+package spcdb
+
+import (
+	"reflect"
+	"sort"
+)
+
+// TrackedRecord remembers the values it started with, so that Update
+// writes only the columns changed since.
+type TrackedRecord struct {
+	Record
+	original Record
+}
+
+// Track starts tracking changes to a copy of rec.
+func Track(rec Record) *TrackedRecord {
+	if rec == nil {
+		rec = NewRecord()
+	}
+	return &TrackedRecord{Record: rec.Clone(), original: rec.Freeze()}
+}
+
+func (t *TrackedRecord) Set(key string, value interface{}) {
+	t.Merge(NewRecord(map[string]interface{}{key: value}))
+}
+
+// Changed lists, sorted, the keys added or set to a different value.
+func (t *TrackedRecord) Changed() []string {
+	changed := make([]string, 0)
+	t.Each(func(key string, value reflect.Value) {
+		prev := t.original.GetRaw(key)
+		if prev == nil || !sameValue(*prev, value) {
+			changed = append(changed, key)
+		}
+	})
+	sort.Strings(changed)
+	return changed
+}
+
+// ChangedRecord holds the changed keys only.
+func (t *TrackedRecord) ChangedRecord() Record {
+	return t.Pick(t.Changed()...)
+}
+
+// Reset takes the current values as the original ones, e.g. once they
+// are saved.
+func (t *TrackedRecord) Reset() {
+	t.original = t.Record.Freeze()
+}
+
+func sameValue(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if !a.CanInterface() || !b.CanInterface() {
+		return false
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,133 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type Tx struct {
+	*sql.Tx
+	driver  string
+	name    string
+	started time.Time
+	caller  string
+	db      *DB
+	// writes are the write events held back until the commit.
+	writes  []WriteEvent
+	mWrites sync.Mutex
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	ret := &Tx{Tx: tx, driver: db.driver, name: db.name, db: db}
+	trackTx(ret)
+	return ret, nil
+}
+
+// Commit commits the transaction and then reports its writes to the
+// OnWrite hooks.
+func (tx *Tx) Commit() error {
+	untrackTx(tx)
+	err := tx.Tx.Commit()
+	writes := tx.takeWrites()
+	if err == nil {
+		for _, ev := range writes {
+			notifyWrite(ev)
+		}
+	}
+	return err
+}
+
+// Rollback rolls the transaction back, dropping its writes unreported.
+func (tx *Tx) Rollback() error {
+	untrackTx(tx)
+	tx.takeWrites()
+	return tx.Tx.Rollback()
+}
+
+func (tx *Tx) queueWrite(ev WriteEvent) {
+	tx.mWrites.Lock()
+	tx.writes = append(tx.writes, ev)
+	tx.mWrites.Unlock()
+}
+
+func (tx *Tx) takeWrites() []WriteEvent {
+	tx.mWrites.Lock()
+	defer tx.mWrites.Unlock()
+	writes := tx.writes
+	tx.writes = nil
+	return writes
+}
+
+func (tx *Tx) queryer() sqlQueryer {
+	return handle{sqlQueryer: tx.Tx, driver: tx.driver, name: tx.name, inTx: true, owner: tx.db}
+}
+
+func (tx *Tx) DriverName() string {
+	return tx.driver
+}
+
+func (tx *Tx) Exists(query string, args ...interface{}) (bool, error) {
+	return exists(context.Background(), tx.queryer(), query, args...)
+}
+
+func (tx *Tx) ExistsContext(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	return exists(ctx, tx.queryer(), query, args...)
+}
+
+func (tx *Tx) ExistsRecord(query string, args ...interface{}) error {
+	return existsRecord(context.Background(), tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryModel(query string, model interface{}, args ...interface{}) error {
+	return queryModel(context.Background(), tx.queryer(), query, model, args...)
+}
+
+func (tx *Tx) QueryModels(query string, dest interface{}, args ...interface{}) error {
+	return queryModels(context.Background(), tx.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryRecords(query string, args ...interface{}) ([]Record, error) {
+	return queryRecords(context.Background(), tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryRecord(query string, args ...interface{}) (Record, error) {
+	return queryRecord(context.Background(), tx.queryer(), query, args...)
+}
+
+func (tx *Tx) ExistsRecordContext(ctx context.Context, query string, args ...interface{}) error {
+	return existsRecord(ctx, tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryModelContext(ctx context.Context, query string, model interface{}, args ...interface{}) error {
+	return queryModel(ctx, tx.queryer(), query, model, args...)
+}
+
+func (tx *Tx) QueryModelsContext(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return queryModels(ctx, tx.queryer(), query, dest, args...)
+}
+
+func (tx *Tx) QueryRecordsContext(ctx context.Context, query string, args ...interface{}) ([]Record, error) {
+	return queryRecords(ctx, tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryRecordContext(ctx context.Context, query string, args ...interface{}) (Record, error) {
+	return queryRecord(ctx, tx.queryer(), query, args...)
+}
+
+func (tx *Tx) QueryEach(query string, fn func(Record) error, args ...interface{}) error {
+	return queryEach(context.Background(), tx.queryer(), query, fn, args...)
+}
+
+func (tx *Tx) QueryEachContext(ctx context.Context, query string, fn func(Record) error, args ...interface{}) error {
+	return queryEach(ctx, tx.queryer(), query, fn, args...)
+}
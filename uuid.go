@@ -0,0 +1,89 @@
+package spcdb
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UUID is a uuid column value. Any [16]byte based type, such as the ones
+// of the popular uuid packages, is decoded in models just as well.
+type UUID [16]byte
+
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	s = strings.Replace(s, "-", "", -1)
+	if len(s) != 32 {
+		return u, fmt.Errorf("spcdb: Invalid UUID '%s'", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+		return u, fmt.Errorf("spcdb: Invalid UUID '%s'", s)
+	}
+	return u, nil
+}
+
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+func (u *UUID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*u = UUID{}
+		return nil
+	case string:
+		parsed, err := ParseUUID(v)
+		*u = parsed
+		return err
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		parsed, err := ParseUUID(string(v))
+		*u = parsed
+		return err
+	}
+	return fmt.Errorf("spcdb: Cannot scan %T into UUID", src)
+}
+
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	return u.Scan(text)
+}
+
+// uuidHook decodes textual uuids into [16]byte based model fields.
+func uuidHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to.Kind() != reflect.Array || to.Len() != 16 || to.Elem().Kind() != reflect.Uint8 {
+		return data, nil
+	}
+	var u UUID
+	switch v := data.(type) {
+	case string:
+		if err := u.Scan(v); err != nil {
+			return nil, err
+		}
+	case []byte:
+		if err := u.Scan(v); err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+	return reflect.ValueOf(u).Convert(to).Interface(), nil
+}
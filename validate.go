@@ -0,0 +1,203 @@
+package spcdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type ModelMismatch struct {
+	Field   string
+	Column  string
+	Problem string
+}
+
+func (m ModelMismatch) String() string {
+	return fmt.Sprintf("%s (column '%s'): %s", m.Field, m.Column, m.Problem)
+}
+
+// ModelMismatchError is returned by ValidateModel when the struct does not
+// fit the table.
+type ModelMismatchError struct {
+	Table      string
+	Mismatches []ModelMismatch
+}
+
+func (e *ModelMismatchError) Error() string {
+	s := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		s[i] = m.String()
+	}
+	return fmt.Sprintf("spcdb: Model does not match table '%s': %s", e.Table, strings.Join(s, "; "))
+}
+
+// ValidateModel checks the fields of model, a struct or a pointer to one,
+// against the live columns of table: every field must have a column of a
+// compatible type, and nullable columns must map to fields able to hold
+// NULL. Mismatches are reported as *ModelMismatchError.
+func ValidateModel(db *DB, table string, model interface{}) error {
+	return ValidateModelContext(context.Background(), db, table, model)
+}
+
+func ValidateModelContext(ctx context.Context, db *DB, table string, model interface{}) error {
+	typ := reflect.TypeOf(model)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("spcdb: Cannot validate %T, need a struct", model)
+	}
+	cols, err := db.ColumnsContext(ctx, table)
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		return fmt.Errorf("spcdb: Table '%s' not found", table)
+	}
+
+	var mismatches []ModelMismatch
+	for _, field := range structInfoFor(typ, db.config().tagName).fields {
+		if db.config().isExcluded(table, field.name) {
+			continue
+		}
+		sf := typ.FieldByIndex(field.index)
+		col := findColumn(cols, field.name)
+		if col == nil {
+			mismatches = append(mismatches, ModelMismatch{sf.Name, field.name, "no such column"})
+			continue
+		}
+		if !fieldFitsColumn(sf.Type, col.Type) {
+			mismatches = append(mismatches, ModelMismatch{sf.Name, col.Name,
+				fmt.Sprintf("%s cannot hold %s", sf.Type, col.Type)})
+		}
+		if col.Nullable && !canHoldNull(sf.Type) {
+			mismatches = append(mismatches, ModelMismatch{sf.Name, col.Name,
+				fmt.Sprintf("column is nullable but %s cannot hold NULL", sf.Type)})
+		}
+	}
+	if len(mismatches) > 0 {
+		return &ModelMismatchError{Table: table, Mismatches: mismatches}
+	}
+	return nil
+}
+
+func findColumn(cols []ColumnInfo, name string) *ColumnInfo {
+	for i := range cols {
+		if cols[i].Name == name {
+			return &cols[i]
+		}
+	}
+	for i := range cols {
+		if strings.EqualFold(cols[i].Name, name) {
+			return &cols[i]
+		}
+	}
+	return nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func canHoldNull(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	}
+	return reflect.PtrTo(typ).Implements(scannerType)
+}
+
+// Column families ColumnFamily groups database types into.
+const (
+	FamilyArray   = "array"
+	FamilyBool    = "bool"
+	FamilyInt     = "int"
+	FamilyNumeric = "numeric"
+	FamilyFloat   = "float"
+	FamilyJSON    = "json"
+	FamilyTime    = "time"
+	FamilyBytes   = "bytes"
+	FamilyString  = "string"
+)
+
+// typeFamilies maps the base names of database types, without size or
+// modifiers, to their family.
+var typeFamilies = map[string]string{
+	"bool": FamilyBool, "boolean": FamilyBool,
+	"tinyint": FamilyInt, "smallint": FamilyInt, "mediumint": FamilyInt, "int": FamilyInt,
+	"integer": FamilyInt, "bigint": FamilyInt, "int2": FamilyInt, "int4": FamilyInt, "int8": FamilyInt,
+	"serial": FamilyInt, "smallserial": FamilyInt, "bigserial": FamilyInt,
+	"serial2": FamilyInt, "serial4": FamilyInt, "serial8": FamilyInt,
+	"numeric": FamilyNumeric, "decimal": FamilyNumeric, "money": FamilyNumeric,
+	"real": FamilyFloat, "double": FamilyFloat, "float": FamilyFloat, "float4": FamilyFloat, "float8": FamilyFloat,
+	"json": FamilyJSON, "jsonb": FamilyJSON,
+	"date": FamilyTime, "datetime": FamilyTime, "time": FamilyTime, "timetz": FamilyTime,
+	"timestamp": FamilyTime, "timestamptz": FamilyTime,
+	"bytea": FamilyBytes, "binary": FamilyBytes, "varbinary": FamilyBytes, "blob": FamilyBytes,
+	"tinyblob": FamilyBytes, "mediumblob": FamilyBytes, "longblob": FamilyBytes,
+	"char": FamilyString, "character": FamilyString, "varchar": FamilyString, "nchar": FamilyString,
+	"nvarchar": FamilyString, "text": FamilyString, "tinytext": FamilyString, "mediumtext": FamilyString,
+	"longtext": FamilyString, "citext": FamilyString, "name": FamilyString, "uuid": FamilyString,
+	"enum": FamilyString, "set": FamilyString,
+}
+
+// ColumnFamily groups a database type name by the Go values it decodes
+// to, matching the name without its size or modifiers, e.g. "int" for
+// "int(11) unsigned"; an empty result means the type is not known.
+func ColumnFamily(dbType string) string {
+	t := strings.ToLower(strings.TrimSpace(dbType))
+	switch {
+	case t == "":
+		return ""
+	case t == "array" || strings.HasSuffix(t, "[]"):
+		return FamilyArray
+	case t == "bit" || strings.HasPrefix(t, "tinyint(1)"):
+		return FamilyBool
+	}
+	if i := strings.IndexAny(t, "( "); i >= 0 {
+		t = t[:i]
+	}
+	return typeFamilies[t]
+}
+
+func fieldFitsColumn(typ reflect.Type, dbType string) bool {
+	family := ColumnFamily(dbType)
+	if family == "" {
+		return true
+	}
+	if typ.Kind() == reflect.Ptr && !typ.Implements(scannerType) {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Interface || reflect.PtrTo(typ).Implements(scannerType) {
+		return true
+	}
+	if family == FamilyJSON {
+		return true
+	}
+	switch typ {
+	case reflect.TypeOf(time.Time{}):
+		return family == FamilyTime || family == FamilyString
+	case ratType, reflect.TypeOf(big.Int{}), reflect.TypeOf(big.Float{}):
+		return family == FamilyNumeric || family == FamilyInt || family == FamilyFloat
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return family == FamilyBool || family == FamilyInt
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return family == FamilyInt || family == FamilyBool
+	case reflect.Float32, reflect.Float64:
+		return family == FamilyFloat || family == FamilyNumeric || family == FamilyInt
+	case reflect.String:
+		return family != FamilyBytes && family != FamilyArray
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return family == FamilyBytes || family == FamilyString
+		}
+		return family == FamilyArray
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package spcdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+)
+
+type WarningKind int
+
+const (
+	// WarnDuplicateColumn: the result has several columns with one name,
+	// only the last of them is kept.
+	WarnDuplicateColumn WarningKind = iota
+	// WarnLossyConversion: a weakly typed conversion lost information,
+	// e.g. a fractional number decoded into an integer field.
+	WarnLossyConversion
+	// WarnUnusedColumn: a result column has no field in the model.
+	WarnUnusedColumn
+	// WarnUnsetField: a model field received no column.
+	WarnUnsetField
+	// WarnTruncatedString: a long argument was cut to LogArgMaxLen in the
+	// query log.
+	WarnTruncatedString
+	// WarnRedactedArg: an argument was hidden from the query log.
+	WarnRedactedArg
+)
+
+type Warning struct {
+	Kind    WarningKind
+	Column  string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// Warnings collects non-fatal issues of the queries run with the context
+// returned by WithWarnings.
+type Warnings struct {
+	list []Warning
+	m    sync.Mutex
+}
+
+type warningsKey struct{}
+
+func WithWarnings(ctx context.Context) (context.Context, *Warnings) {
+	w := &Warnings{}
+	return context.WithValue(ctx, warningsKey{}, w), w
+}
+
+func (w *Warnings) List() []Warning {
+	w.m.Lock()
+	defer w.m.Unlock()
+	ret := make([]Warning, len(w.list))
+	copy(ret, w.list)
+	return ret
+}
+
+func (w *Warnings) Len() int {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return len(w.list)
+}
+
+func (w *Warnings) add(kind WarningKind, column, format string, args ...interface{}) {
+	if w == nil {
+		return
+	}
+	w.m.Lock()
+	w.list = append(w.list, Warning{kind, column, fmt.Sprintf(format, args...)})
+	w.m.Unlock()
+}
+
+func warningsFrom(ctx context.Context) *Warnings {
+	w, _ := ctx.Value(warningsKey{}).(*Warnings)
+	return w
+}
+
+// queryColumns returns the result columns, reporting duplicated names.
+func queryColumns(ctx context.Context, cols []string) []string {
+	w := warningsFrom(ctx)
+	if w == nil {
+		return cols
+	}
+	seen := make(map[string]bool, len(cols))
+	for _, col := range cols {
+		if seen[col] {
+			w.add(WarnDuplicateColumn, col, "spcdb: Duplicate column '%s' in result, earlier values are ignored", col)
+		}
+		seen[col] = true
+	}
+	return cols
+}
+
+// lossyHook reports weak conversions mapstructure performs silently.
+func lossyHook(w *Warnings) DecodeHook {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		val := reflect.ValueOf(data)
+		switch to.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			switch from.Kind() {
+			case reflect.Float32, reflect.Float64:
+				if f := val.Float(); f != math.Trunc(f) {
+					w.add(WarnLossyConversion, "", "spcdb: %v truncated to integer %s", data, to)
+				}
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if reflect.Zero(to).OverflowInt(val.Int()) {
+					w.add(WarnLossyConversion, "", "spcdb: %v overflows %s", data, to)
+				}
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			switch from.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if val.Int() < 0 || reflect.Zero(to).OverflowUint(uint64(val.Int())) {
+					w.add(WarnLossyConversion, "", "spcdb: %v overflows %s", data, to)
+				}
+			}
+		case reflect.Bool:
+			switch from.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if v := val.Int(); v != 0 && v != 1 {
+					w.add(WarnLossyConversion, "", "spcdb: %v converted to bool", data)
+				}
+			}
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,39 @@
+package spcdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWarnTruncatedAndRedactedArgs(t *testing.T) {
+	db := openNop(t)
+	const query = "UPDATE warned SET secret = ?, body = ?"
+	SetLogger(LoggerFunc(func(context.Context, QueryLog) {}), LogOptions{
+		Redact: func(q string, i int) bool { return q == query && i == 0 },
+	})
+
+	ctx, w := WithWarnings(context.Background())
+	long := strings.Repeat("x", LogArgMaxLen+1)
+	if _, err := db.ExecAffectedContext(ctx, query, "hunter2", long); err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[WarningKind]int)
+	for _, warning := range w.List() {
+		kinds[warning.Kind]++
+	}
+	if kinds[WarnRedactedArg] != 1 {
+		t.Errorf("%d redacted argument warnings, want 1: %v", kinds[WarnRedactedArg], w.List())
+	}
+	if kinds[WarnTruncatedString] != 1 {
+		t.Errorf("%d truncated string warnings, want 1: %v", kinds[WarnTruncatedString], w.List())
+	}
+
+	ctx, w = WithWarnings(context.Background())
+	if _, err := db.ExecAffectedContext(ctx, "UPDATE warned SET body = ?", "short"); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.Len(); n != 0 {
+		t.Errorf("%d warnings for a short unredacted argument: %v", n, w.List())
+	}
+}
@@ -0,0 +1,134 @@
+package spcdb
+
+import (
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type StuckTx struct {
+	Caller     string
+	Started    time.Time
+	Age        time.Duration
+	RolledBack bool
+}
+
+// TxWatchdogHook is called for every transaction found open longer than
+// the watchdog limit.
+var TxWatchdogHook = func(stuck StuckTx) {
+	log.Printf("spcdb: Transaction open for %s, begun at %s (rolled back: %t)",
+		stuck.Age, stuck.Caller, stuck.RolledBack)
+}
+
+var openTxs = make(map[*Tx]struct{}, 40)
+var mTxs sync.Mutex
+
+var watchdogStop chan struct{}
+var mWatchdog sync.Mutex
+
+// watchdogRunning is set while a watchdog runs; transactions begun
+// otherwise are not tracked.
+var watchdogRunning int32
+
+// StartTxWatchdog periodically reports transactions held open longer than
+// maxAge and, if rollback is set, rolls them back. Only transactions begun
+// while a watchdog runs are watched.
+func StartTxWatchdog(maxAge time.Duration, rollback bool) {
+	StopTxWatchdog()
+	interval := maxAge / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	stop := make(chan struct{})
+	mWatchdog.Lock()
+	watchdogStop = stop
+	atomic.StoreInt32(&watchdogRunning, 1)
+	mWatchdog.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+				checkStuckTxs(maxAge, rollback)
+			}
+		}
+	}()
+}
+
+func StopTxWatchdog() {
+	mWatchdog.Lock()
+	defer mWatchdog.Unlock()
+	if watchdogStop != nil {
+		close(watchdogStop)
+		watchdogStop = nil
+	}
+	atomic.StoreInt32(&watchdogRunning, 0)
+}
+
+func checkStuckTxs(maxAge time.Duration, rollback bool) {
+	now := time.Now()
+	stuck := make([]*Tx, 0)
+	infos := make([]StuckTx, 0)
+	mTxs.Lock()
+	for tx := range openTxs {
+		if now.Sub(tx.started) > maxAge {
+			stuck = append(stuck, tx)
+			infos = append(infos, StuckTx{Caller: tx.caller, Started: tx.started, Age: now.Sub(tx.started)})
+			if rollback {
+				// Claimed here, so a concurrent Commit finds it untracked.
+				delete(openTxs, tx)
+			}
+		}
+	}
+	mTxs.Unlock()
+
+	for i, tx := range stuck {
+		info := infos[i]
+		if rollback {
+			info.RolledBack = tx.Rollback() == nil
+		}
+		TxWatchdogHook(info)
+	}
+}
+
+// trackTx and untrackTx guard openTxs and the fields of tx it reads with
+// mTxs, as Commit and Rollback may run on any goroutine. Untracking is
+// unconditional, for transactions begun before the watchdog stopped.
+func trackTx(tx *Tx) {
+	if atomic.LoadInt32(&watchdogRunning) == 0 {
+		return
+	}
+	caller := callSite()
+	mTxs.Lock()
+	tx.started = time.Now()
+	tx.caller = caller
+	openTxs[tx] = struct{}{}
+	mTxs.Unlock()
+}
+
+func untrackTx(tx *Tx) {
+	mTxs.Lock()
+	delete(openTxs, tx)
+	mTxs.Unlock()
+}
+
+// callSite returns the first caller outside of this package.
+func callSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/jenchik/spcdb.") {
+			return frame.Function + " " + frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
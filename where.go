@@ -0,0 +1,141 @@
+package spcdb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WhereOpSeparator splits a condition key into column and operator,
+// e.g. "age__gt" or "name__like". Keys without an operator mean "eq".
+var WhereOpSeparator = "__"
+
+var whereOps = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"gt":   ">",
+	"ge":   ">=",
+	"lt":   "<",
+	"le":   "<=",
+	"like": "LIKE",
+	"in":   "IN",
+	"nin":  "NOT IN",
+	"null": "",
+}
+
+// BuildWhere renders conds as a parameterized condition joined with AND,
+// using '?' placeholders (rebound by the query builder). Entries with an
+// unknown operator or an unsafe column name are an error.
+func BuildWhere(conds Record) (string, []interface{}, error) {
+	if conds == nil {
+		return "", nil, nil
+	}
+	keys := make([]string, 0)
+	conds.Each(func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		cond, condArgs, err := whereCond(key, conds.Get(key))
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, cond)
+		args = append(args, condArgs...)
+	}
+	return strings.Join(parts, " AND "), args, nil
+}
+
+// WhereRecord adds the conditions of BuildWhere; its error is returned
+// by the query methods of the builder, and SQL renders a condition
+// matching nothing in its place.
+func (b *SelectBuilder) WhereRecord(conds Record) *SelectBuilder {
+	where, args, err := BuildWhere(conds)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b.Where("1 = 0")
+	}
+	if where == "" {
+		return b
+	}
+	return b.Where(where, args...)
+}
+
+func whereCond(key string, value interface{}) (string, []interface{}, error) {
+	column, op := key, "eq"
+	if i := strings.LastIndex(key, WhereOpSeparator); i > 0 {
+		column, op = key[:i], key[i+len(WhereOpSeparator):]
+	}
+	sqlOp, found := whereOps[op]
+	if !found {
+		return "", nil, fmt.Errorf("spcdb: Unknown where operator '%s' in '%s'", op, key)
+	}
+	if !isIdentifier(column) {
+		return "", nil, fmt.Errorf("spcdb: Invalid column name '%s'", column)
+	}
+
+	switch op {
+	case "null":
+		isNull, ok := value.(bool)
+		if !ok {
+			return "", nil, fmt.Errorf("spcdb: Operator 'null' expects bool for '%s'", column)
+		}
+		if isNull {
+			return column + " IS NULL", nil, nil
+		}
+		return column + " IS NOT NULL", nil, nil
+	case "in", "nin":
+		list := normalizeValue(reflect.ValueOf(value))
+		if !list.IsValid() || (list.Kind() != reflect.Slice && list.Kind() != reflect.Array) {
+			return "", nil, fmt.Errorf("spcdb: Operator '%s' expects a slice for '%s'", op, column)
+		}
+		if list.Len() == 0 {
+			if op == "in" {
+				return "1 = 0", nil, nil
+			}
+			return "1 = 1", nil, nil
+		}
+		args := make([]interface{}, list.Len())
+		marks := make([]string, list.Len())
+		for i := range args {
+			args[i] = list.Index(i).Interface()
+			marks[i] = "?"
+		}
+		return column + " " + sqlOp + " (" + strings.Join(marks, ", ") + ")", args, nil
+	case "eq", "ne":
+		if value == nil {
+			if op == "eq" {
+				return column + " IS NULL", nil, nil
+			}
+			return column + " IS NOT NULL", nil, nil
+		}
+	}
+	return column + " " + sqlOp + " ?", []interface{}{value}, nil
+}
+
+// isIdentifier accepts plain, optionally qualified, column names.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, part := range strings.Split(name, ".") {
+		if part == "" {
+			return false
+		}
+		for i, c := range part {
+			switch {
+			case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			case c >= '0' && c <= '9' && i > 0:
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
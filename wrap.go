@@ -0,0 +1,38 @@
+package spcdb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// NewFromSQLDB lets the helpers run on a *sql.DB opened elsewhere, e.g. by
+// an instrumented driver or by pgx's stdlib.OpenDBFromPool. The driver
+// name, which decides the placeholder style, is guessed from the driver
+// type and falls back to DefaultDriverName. Closing the DB closes db.
+func NewFromSQLDB(db *sql.DB) *DB {
+	return NewFromSQLDBDriver(db, driverNameOf(db))
+}
+
+func NewFromSQLDBDriver(db *sql.DB, driverName string) *DB {
+	return &DB{DB: db, driver: driverName, stmts: newStmtCache(StmtCacheSize)}
+}
+
+func driverNameOf(db *sql.DB) string {
+	typeName := fmt.Sprintf("%T", db.Driver())
+	switch {
+	case strings.HasPrefix(typeName, "*pq."):
+		return "postgres"
+	case strings.HasPrefix(typeName, "*stdlib."):
+		return "pgx"
+	case strings.HasPrefix(typeName, "*mysql."), strings.HasPrefix(typeName, "mysql."):
+		return "mysql"
+	case strings.HasPrefix(typeName, "*sqlite3."):
+		return "sqlite3"
+	case strings.HasPrefix(typeName, "*sqlite."):
+		return "sqlite"
+	case strings.HasPrefix(typeName, "*mssql."):
+		return "sqlserver"
+	}
+	return DefaultDriverName
+}
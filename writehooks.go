@@ -0,0 +1,87 @@
+package spcdb
+
+import (
+	"regexp"
+	"sync"
+)
+
+type WriteOp int
+
+const (
+	WriteInsert WriteOp = iota
+	WriteUpdate
+	WriteDelete
+)
+
+func (op WriteOp) String() string {
+	switch op {
+	case WriteInsert:
+		return "insert"
+	case WriteUpdate:
+		return "update"
+	}
+	return "delete"
+}
+
+// WriteEvent describes a successful write of the Insert, Update and Delete
+// builders and of InsertBatch.
+type WriteEvent struct {
+	Op    WriteOp
+	Table string
+	// Keys identify the written rows: the inserted values for inserts,
+	// the columns compared with "col = ?" in the WHERE clause otherwise.
+	// Nil when the rows cannot be told, e.g. for a range update.
+	Keys []Record
+	// InTx is set for writes in a transaction; they are reported once it
+	// commits and not at all when it rolls back.
+	InTx bool
+}
+
+var writeHooks []func(WriteEvent)
+var mWriteHooks sync.RWMutex
+
+// OnWrite registers fn to be called after every write of the helpers, e.g.
+// to invalidate cached entries of the table. Writes in a transaction are
+// reported after its commit.
+func OnWrite(fn func(WriteEvent)) {
+	mWriteHooks.Lock()
+	writeHooks = append(writeHooks, fn)
+	mWriteHooks.Unlock()
+}
+
+func fireWrite(q Queryer, ev WriteEvent) {
+	if tx, ok := q.(*Tx); ok {
+		ev.InTx = true
+		tx.queueWrite(ev)
+		return
+	}
+	notifyWrite(ev)
+}
+
+func notifyWrite(ev WriteEvent) {
+	mWriteHooks.RLock()
+	hooks := writeHooks
+	mWriteHooks.RUnlock()
+	for _, fn := range hooks {
+		fn(ev)
+	}
+}
+
+var keyCondRe = regexp.MustCompile("^\\s*[\"`\\[]?(\\w+)[\"`\\]]?\\s*=\\s*\\?\\s*$")
+
+// whereKeys picks the equality conditions out of the WHERE clause; nil
+// unless every condition is one.
+func whereKeys(where []string, args []interface{}) []Record {
+	if len(where) == 0 || len(where) != len(args) {
+		return nil
+	}
+	keys := make(map[string]interface{}, len(where))
+	for i, cond := range where {
+		m := keyCondRe.FindStringSubmatch(cond)
+		if m == nil {
+			return nil
+		}
+		keys[m[1]] = args[i]
+	}
+	return []Record{NewRecord(keys)}
+}
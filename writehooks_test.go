@@ -0,0 +1,97 @@
+package spcdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// nopDriver accepts every statement and transaction and returns no rows.
+type nopDriver struct{}
+type nopConn struct{}
+type nopStmt struct{}
+
+func (nopDriver) Open(string) (driver.Conn, error) { return nopConn{}, nil }
+
+func (nopConn) Prepare(string) (driver.Stmt, error) { return nopStmt{}, nil }
+func (nopConn) Close() error                        { return nil }
+func (nopConn) Begin() (driver.Tx, error)           { return nopConn{}, nil }
+func (nopConn) Commit() error                       { return nil }
+func (nopConn) Rollback() error                     { return nil }
+
+func (nopStmt) Close() error                               { return nil }
+func (nopStmt) NumInput() int                              { return -1 }
+func (nopStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (nopStmt) Query([]driver.Value) (driver.Rows, error)  { return nil, errors.New("nop: no rows") }
+
+var registerNop sync.Once
+
+func openNop(t *testing.T) *DB {
+	registerNop.Do(func() { sql.Register("spcdb_nop", nopDriver{}) })
+	db, err := Open("spcdb_nop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestWriteHooksWaitForCommit(t *testing.T) {
+	db := openNop(t)
+	var m sync.Mutex
+	var events []WriteEvent
+	OnWrite(func(ev WriteEvent) {
+		if ev.Table == "write_hooks" {
+			m.Lock()
+			events = append(events, ev)
+			m.Unlock()
+		}
+	})
+	fired := func() int {
+		m.Lock()
+		defer m.Unlock()
+		return len(events)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Delete("write_hooks").Where("id = ?", 1).Exec(tx); err != nil {
+		t.Fatal(err)
+	}
+	if n := fired(); n != 0 {
+		t.Fatalf("%d events before the end of the transaction", n)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if n := fired(); n != 0 {
+		t.Fatalf("%d events after rollback, want none", n)
+	}
+
+	if tx, err = db.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Delete("write_hooks").Where("id = ?", 2).Exec(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n := fired(); n != 1 {
+		t.Fatalf("%d events after commit, want 1", n)
+	}
+	if ev := events[0]; !ev.InTx || ev.Op != WriteDelete || ev.Keys[0].Get("id") != 2 {
+		t.Errorf("event = %+v", ev)
+	}
+
+	if _, err = Delete("write_hooks").Where("id = ?", 3).Exec(db); err != nil {
+		t.Fatal(err)
+	}
+	if n := fired(); n != 2 {
+		t.Fatalf("%d events after a write outside a transaction, want 2", n)
+	}
+}